@@ -3,19 +3,22 @@ package handlers
 import (
 	"bytes"
 	"chat2sr/config"
+	"chat2sr/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"strings"
 	
 	"github.com/gin-gonic/gin"
 )
 
-// AnalysisRequest 分析请求
+// AnalysisRequest 分析请求，结果和SQL均从服务端结果存储中读取
 type AnalysisRequest struct {
-	Query  string        `json:"query"`
-	SQL    string        `json:"sql"`
-	Result []interface{} `json:"result"`
+	Query    string `json:"query"`
+	ResultID string `json:"result_id"`
 }
 
 // AnalysisResponse 分析响应
@@ -33,8 +36,30 @@ func HandleAnalysis(c *gin.Context) {
 		return
 	}
 
+	if strings.TrimSpace(req.ResultID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide result_id"})
+		return
+	}
+
+	result, err := services.LoadResult(req.ResultID)
+	if err != nil {
+		if errors.Is(err, services.ErrResultNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Result not found or expired"})
+			return
+		}
+		log.Printf("Error loading result %s: %s", req.ResultID, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load query result"})
+		return
+	}
+
+	// 优先使用执行时保存的用户需求
+	query := result.Query
+	if query == "" {
+		query = req.Query
+	}
+
 	// 调用DeepSeek API生成分析报告
-	analysis, err := generateAnalysisReport(req.Query, req.SQL, req.Result)
+	analysis, err := generateAnalysisReport(query, result.SQL, result.Rows)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, AnalysisResponse{
 			Error: fmt.Sprintf("生成分析报告失败: %v", err),
@@ -49,7 +74,7 @@ func HandleAnalysis(c *gin.Context) {
 }
 
 // generateAnalysisReport 生成分析报告
-func generateAnalysisReport(query, sql string, result []interface{}) (string, error) {
+func generateAnalysisReport(query, sql string, result []map[string]interface{}) (string, error) {
 	// 构建请求DeepSeek API的数据
 	resultJSON, err := json.Marshal(result)
 	if err != nil {
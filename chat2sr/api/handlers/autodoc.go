@@ -0,0 +1,98 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleRunAutoDoc 在后台为缺少注释的表和字段生成待审核的描述
+func HandleRunAutoDoc(c *gin.Context) {
+    var req models.AutoDocRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    // 需要逐表采样和调用模型，耗时较长，结果通过待审核列表查看
+    if err := services.StartAutoDoc(req.Tables, req.MaxTables); err != nil {
+        c.JSON(http.StatusConflict, gin.H{"error": "Auto documentation is already running"})
+        return
+    }
+
+    c.JSON(http.StatusAccepted, gin.H{"status": "running"})
+}
+
+// HandleListProposals 列出模型生成的描述，支持 status 和 table 过滤
+func HandleListProposals(c *gin.Context) {
+    list := services.ListProposals(c.DefaultQuery("status", services.ProposalStatusPending), c.Query("table"))
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(list), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "proposals": list[start:end],
+        "total":     len(list),
+        "page":      page,
+        "page_size": pageSize,
+    })
+}
+
+// HandleApproveProposal 审核通过描述并写入补充元数据，可以在请求中修改描述
+func HandleApproveProposal(c *gin.Context) {
+    var req models.ReviewProposalRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    proposal, err := services.ApproveProposal(c.Param("id"), req.Description, req.Reviewer)
+    if err != nil {
+        respondProposalError(c, err)
+        return
+    }
+    log.Printf("Approved description for %s.%s", proposal.Table, proposal.Column)
+
+    c.JSON(http.StatusOK, proposal)
+}
+
+// HandleRejectProposal 驳回描述
+func HandleRejectProposal(c *gin.Context) {
+    var req models.ReviewProposalRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    proposal, err := services.RejectProposal(c.Param("id"), req.Reviewer)
+    if err != nil {
+        respondProposalError(c, err)
+        return
+    }
+    log.Printf("Rejected description for %s.%s", proposal.Table, proposal.Column)
+
+    c.JSON(http.StatusOK, proposal)
+}
+
+func respondProposalError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrProposalNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found"})
+    case errors.Is(err, services.ErrProposalReviewed):
+        c.JSON(http.StatusConflict, gin.H{"error": "Proposal has already been reviewed"})
+    default:
+        log.Printf("Error reviewing proposal: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review proposal"})
+    }
+}
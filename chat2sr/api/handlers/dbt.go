@@ -0,0 +1,45 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/config"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleImportDbt 从 dbt 的 target 目录导入描述、标签、关联关系和取值字典，未指定路径时使用 DBT_TARGET_DIR
+func HandleImportDbt(c *gin.Context) {
+    var req models.DbtImportRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+    path := strings.TrimSpace(req.Path)
+    if path == "" {
+        path = config.AppConfig.DbtTargetDir
+    }
+    if path == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "dbt target path is required"})
+        return
+    }
+
+    summary, err := services.ImportDbt(path)
+    if err != nil {
+        log.Printf("Error importing dbt project: %s", err.Error())
+        if errors.Is(err, services.ErrDbtManifestMissing) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import dbt project"})
+        return
+    }
+
+    c.JSON(http.StatusOK, summary)
+}
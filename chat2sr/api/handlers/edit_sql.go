@@ -0,0 +1,74 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleEditSQL 按自然语言的修改要求改写已有SQL，返回改写后的SQL和差异。
+// 可以直接传入SQL，也可以传入历史记录ID修改其中的SQL
+func HandleEditSQL(c *gin.Context) {
+    var req models.EditSQLRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    sqlText := req.SQL
+    if strings.TrimSpace(sqlText) == "" && req.HistoryID != "" {
+        entry, err := services.GetHistoryEntry(req.HistoryID)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+            return
+        }
+        sqlText = entry.SQL
+    }
+
+    edit, err := services.EditSQL(sqlText, req.Instruction)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrSQLEditInvalid):
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        case errors.Is(err, services.ErrSQLInvalid):
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+        default:
+            log.Printf("Error editing SQL: %s", err.Error())
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit SQL"})
+        }
+        return
+    }
+    log.Printf("Edited SQL: %s", edit.SQL)
+
+    // 在对话中修改时记为一轮追问，后续问题可以在改写后的SQL上继续
+    if req.SessionID != "" {
+        recordSessionTurn(req.SessionID, req.Instruction, edit.SQL, edit.Tables)
+    }
+    historyID, err := services.RecordHistory(services.HistoryEntry{
+        User:      req.User,
+        SessionID: req.SessionID,
+        Question:  req.Instruction,
+        SQL:       edit.SQL,
+        Tables:    edit.Tables,
+        Source:    "edit",
+        Status:    services.HistoryStatusGenerated,
+    })
+    if err != nil {
+        log.Printf("Error recording history: %s", err.Error())
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "sql":        edit.SQL,
+        "diff":       edit.Diff,
+        "changed":    edit.Changed,
+        "tables":     edit.Tables,
+        "joins":      edit.Joins,
+        "source":     "edit",
+        "history_id": historyID,
+    })
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleListExamples 列出示例问题，支持 q 参数过滤
+func HandleListExamples(c *gin.Context) {
+    list := services.ListExamples(c.Query("q"))
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(list), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "examples":  list[start:end],
+        "total":     len(list),
+        "page":      page,
+        "page_size": pageSize,
+    })
+}
+
+// HandleGetExample 获取单个示例
+func HandleGetExample(c *gin.Context) {
+    example, err := services.GetExample(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Example not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, example)
+}
+
+// HandleCreateExample 新增示例
+func HandleCreateExample(c *gin.Context) {
+    var req models.ExampleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    example, err := services.CreateExample(services.SQLExample{
+        Question: req.Question,
+        SQL:      req.SQL,
+        Notes:    req.Notes,
+        Source:   "manual",
+    })
+    if err != nil {
+        respondExampleError(c, err)
+        return
+    }
+    log.Printf("Created example %s: %s", example.ID, example.Question)
+
+    c.JSON(http.StatusCreated, example)
+}
+
+// HandleUpdateExample 修改示例
+func HandleUpdateExample(c *gin.Context) {
+    var req models.ExampleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    example, err := services.UpdateExample(c.Param("id"), services.SQLExample{
+        Question: req.Question,
+        SQL:      req.SQL,
+        Notes:    req.Notes,
+    })
+    if err != nil {
+        respondExampleError(c, err)
+        return
+    }
+    log.Printf("Updated example %s", example.ID)
+
+    c.JSON(http.StatusOK, example)
+}
+
+// HandleDeleteExample 删除示例
+func HandleDeleteExample(c *gin.Context) {
+    id := c.Param("id")
+    if err := services.DeleteExample(id); err != nil {
+        respondExampleError(c, err)
+        return
+    }
+    log.Printf("Deleted example %s", id)
+
+    c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// HandleSaveResultAsExample 将已执行的查询保存为示例，可以修正问题描述
+func HandleSaveResultAsExample(c *gin.Context) {
+    var req models.SaveExampleRequest
+    // 请求体可以为空
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    example, err := services.SaveResultAsExample(c.Param("id"), req.Question, req.Notes)
+    if err != nil {
+        if errors.Is(err, services.ErrResultNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+            return
+        }
+        respondExampleError(c, err)
+        return
+    }
+    log.Printf("Saved result %s as example %s", c.Param("id"), example.ID)
+
+    c.JSON(http.StatusCreated, example)
+}
+
+func respondExampleError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrExampleNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Example not found"})
+    case errors.Is(err, services.ErrExampleInvalid):
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide a question and SQL"})
+    default:
+        log.Printf("Error saving example: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save example"})
+    }
+}
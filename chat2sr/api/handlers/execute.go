@@ -1,9 +1,12 @@
 package handlers
 
 import (
+    "errors"
+    "fmt"
     "log"
     "net/http"
     "strings"
+    "time"
     "github.com/gin-gonic/gin"
     "chat2sr/services"
     "chat2sr/api/models"
@@ -23,16 +26,89 @@ func HandleExecute(c *gin.Context) {
         return
     }
 
-    log.Printf("Executing SQL: %s", req.SQL)
+    entry := services.HistoryEntry{
+        User:      req.User,
+        SessionID: req.SessionID,
+        Question:  req.Query,
+        SQL:       req.SQL,
+        Tables:    services.ExtractSQLTables(req.SQL),
+        Source:    "execute",
+    }
+    executeAndRespond(c, entry, req.HistoryID)
+}
+
+// executeAndRespond 执行SQL并保存结果，执行情况记录到查询历史：historyID 对应生成SQL时的记录，不存在时新增一条。
+// entry.Args 为绑定到 ? 占位符的参数
+func executeAndRespond(c *gin.Context, entry services.HistoryEntry, historyID string) {
+    log.Printf("Executing SQL: %s %v", entry.SQL, entry.Args)
 
-    results, err := services.ExecuteSQL(req.SQL)
+    started := time.Now()
+    columns, results, err := services.ExecuteSQLContext(c.Request.Context(), entry.SQL, nil, entry.Args...)
     if err != nil {
         log.Printf("Error executing SQL: %s", err.Error())
+        recordExecution(entry, historyID, "", 0, time.Since(started), err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute SQL"})
         return
     }
+    duration := time.Since(started)
+
+    // 结果保存在服务端，后续分页、排序、下载和分析都通过 result_id 引用
+    resultID, err := services.SaveResult(entry.Question, entry.SQL, columns, results)
+    if err != nil {
+        log.Printf("Error saving result: %s", err.Error())
+        // SQL 已执行，结果无法保存时同样记录到历史，避免记录一直停留在已生成状态
+        recordExecution(entry, historyID, "", len(results), duration, fmt.Errorf("failed to save result: %v", err))
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save query result"})
+        return
+    }
+    historyID = recordExecution(entry, historyID, resultID, len(results), duration, nil)
+
+    // 执行成功的SQL中的关联条件用于后续的关联路径推断
+    go services.LearnJoins(entry.SQL)
 
-    c.JSON(http.StatusOK, gin.H{
-        "results": results,
-    })
-}
\ No newline at end of file
+    // 结果摘要记录到对话中，供后续追问参考
+    if entry.SessionID != "" {
+        if err := services.RecordSessionResult(entry.SessionID, entry.SQL, resultID, columns, len(results)); err != nil {
+            log.Printf("Error recording result for session %s: %s", entry.SessionID, err.Error())
+        }
+    }
+
+    page, pageSize := parsePagination(c)
+    resultPage, err := services.GetResultPage(resultID, page, pageSize, "", false)
+    if err != nil {
+        log.Printf("Error reading result %s: %s", resultID, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read query result"})
+        return
+    }
+    resultPage.HistoryID = historyID
+
+    c.JSON(http.StatusOK, resultPage)
+}
+
+// recordExecution 记录执行结果，失败只写日志，返回对应的历史记录ID
+func recordExecution(entry services.HistoryEntry, historyID, resultID string, rowCount int, duration time.Duration, execErr error) string {
+    if historyID != "" {
+        err := services.RecordExecution(historyID, resultID, rowCount, duration, execErr)
+        if err == nil {
+            return historyID
+        }
+        if !errors.Is(err, services.ErrHistoryNotFound) {
+            log.Printf("Error recording execution for history %s: %s", historyID, err.Error())
+            return historyID
+        }
+    }
+
+    entry.Status = services.HistoryStatusSucceeded
+    entry.DurationMs = duration.Milliseconds()
+    entry.RowCount = rowCount
+    entry.ResultID = resultID
+    if execErr != nil {
+        entry.Status = services.HistoryStatusFailed
+        entry.Error = execErr.Error()
+    }
+    id, err := services.RecordHistory(entry)
+    if err != nil {
+        log.Printf("Error recording history: %s", err.Error())
+    }
+    return id
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleExplainSQL 用中文或英文逐步解释SQL，可以直接传入SQL，也可以传入历史记录ID解释生成的SQL
+func HandleExplainSQL(c *gin.Context) {
+    var req models.ExplainSQLRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    sqlText := req.SQL
+    if strings.TrimSpace(sqlText) == "" && req.HistoryID != "" {
+        entry, err := services.GetHistoryEntry(req.HistoryID)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+            return
+        }
+        sqlText = entry.SQL
+    }
+
+    explanation, err := services.ExplainSQL(sqlText, req.Language)
+    if err != nil {
+        if errors.Is(err, services.ErrExplainInvalid) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        log.Printf("Error explaining SQL: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain SQL"})
+        return
+    }
+
+    c.JSON(http.StatusOK, explanation)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleListGlossary 列出业务术语，支持 q 参数过滤
+func HandleListGlossary(c *gin.Context) {
+    entries := services.ListGlossary(c.Query("q"))
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(entries), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "entries":   entries[start:end],
+        "total":     len(entries),
+        "page":      page,
+        "page_size": pageSize,
+    })
+}
+
+// HandleGetGlossaryEntry 获取单个业务术语
+func HandleGetGlossaryEntry(c *gin.Context) {
+    entry, err := services.GetGlossaryEntry(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Glossary entry not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, entry)
+}
+
+// HandleCreateGlossaryEntry 新增业务术语
+func HandleCreateGlossaryEntry(c *gin.Context) {
+    var req models.GlossaryEntryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    entry, err := services.CreateGlossaryEntry(glossaryEntryFromRequest(req))
+    if err != nil {
+        respondGlossaryError(c, err)
+        return
+    }
+    log.Printf("Created glossary entry %s: %s", entry.ID, entry.Term)
+
+    c.JSON(http.StatusCreated, entry)
+}
+
+// HandleUpdateGlossaryEntry 修改业务术语
+func HandleUpdateGlossaryEntry(c *gin.Context) {
+    var req models.GlossaryEntryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    entry, err := services.UpdateGlossaryEntry(c.Param("id"), glossaryEntryFromRequest(req))
+    if err != nil {
+        respondGlossaryError(c, err)
+        return
+    }
+    log.Printf("Updated glossary entry %s: %s", entry.ID, entry.Term)
+
+    c.JSON(http.StatusOK, entry)
+}
+
+// HandleDeleteGlossaryEntry 删除业务术语
+func HandleDeleteGlossaryEntry(c *gin.Context) {
+    id := c.Param("id")
+    if err := services.DeleteGlossaryEntry(id); err != nil {
+        respondGlossaryError(c, err)
+        return
+    }
+    log.Printf("Deleted glossary entry %s", id)
+
+    c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+func glossaryEntryFromRequest(req models.GlossaryEntryRequest) services.GlossaryEntry {
+    return services.GlossaryEntry{
+        Term:        req.Term,
+        Synonyms:    req.Synonyms,
+        Description: req.Description,
+        Tables:      req.Tables,
+        Columns:     req.Columns,
+        Expression:  req.Expression,
+        Filter:      req.Filter,
+    }
+}
+
+func respondGlossaryError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrGlossaryNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Glossary entry not found"})
+    case errors.Is(err, services.ErrGlossaryInvalid):
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide a term"})
+    case errors.Is(err, services.ErrGlossaryTermExists):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        log.Printf("Error saving glossary: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save glossary"})
+    }
+}
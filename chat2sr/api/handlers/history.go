@@ -0,0 +1,115 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// 日期过滤参数的格式
+const historyDateLayout = "2006-01-02"
+
+// HandleListHistory 检索查询历史，支持 q 全文检索和 user、table、status、from、to（含当天）过滤
+func HandleListHistory(c *gin.Context) {
+    filter := services.HistoryFilter{
+        Query:  c.Query("q"),
+        User:   c.Query("user"),
+        Table:  c.Query("table"),
+        Status: c.Query("status"),
+    }
+    var err error
+    if from := c.Query("from"); from != "" {
+        if filter.From, err = time.ParseInLocation(historyDateLayout, from, time.Local); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+            return
+        }
+    }
+    if to := c.Query("to"); to != "" {
+        if filter.To, err = time.ParseInLocation(historyDateLayout, to, time.Local); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+            return
+        }
+        filter.To = filter.To.AddDate(0, 0, 1)
+    }
+
+    list := services.SearchHistory(filter)
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(list), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "history":   list[start:end],
+        "total":     len(list),
+        "page":      page,
+        "page_size": pageSize,
+    })
+}
+
+// HandleGetHistory 获取单条历史记录
+func HandleGetHistory(c *gin.Context) {
+    entry, err := services.GetHistoryEntry(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, entry)
+}
+
+// HandleRerunHistory 重新执行历史记录中的SQL，执行情况作为新的历史记录保存
+func HandleRerunHistory(c *gin.Context) {
+    previous, err := services.GetHistoryEntry(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+        return
+    }
+    if strings.TrimSpace(previous.SQL) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "History entry has no SQL to run"})
+        return
+    }
+
+    entry := services.HistoryEntry{
+        User:     c.DefaultQuery("user", previous.User),
+        Question: previous.Question,
+        SQL:      previous.SQL,
+        Args:     previous.Args,
+        Tables:   previous.Tables,
+        Source:   "rerun",
+        RerunOf:  previous.ID,
+    }
+    executeAndRespond(c, entry, "")
+}
+
+// HandleSaveHistoryAsExample 将历史查询保存为示例，可以修正问题描述
+func HandleSaveHistoryAsExample(c *gin.Context) {
+    var req models.SaveExampleRequest
+    // 请求体可以为空
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    example, err := services.SaveHistoryAsExample(c.Param("id"), req.Question, req.Notes)
+    if err != nil {
+        if errors.Is(err, services.ErrHistoryNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+            return
+        }
+        if errors.Is(err, services.ErrHistoryNotVerified) {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        respondExampleError(c, err)
+        return
+    }
+    log.Printf("Saved history %s as example %s", c.Param("id"), example.ID)
+
+    c.JSON(http.StatusCreated, example)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+const (
+    defaultPageSize = 100
+    maxPageSize     = 1000
+)
+
+// HandleSubmitJob 提交异步SQL查询任务
+func HandleSubmitJob(c *gin.Context) {
+    var req models.JobSubmitRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    if strings.TrimSpace(req.SQL) == "" {
+        log.Printf("Empty SQL received")
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide SQL statement"})
+        return
+    }
+
+    jobID, err := services.SubmitJob(req.SQL, req.User)
+    if err != nil {
+        log.Printf("Error submitting job: %s", err.Error())
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many queued jobs, please retry later"})
+        return
+    }
+    log.Printf("Submitted job %s: %s", jobID, req.SQL)
+
+    c.JSON(http.StatusAccepted, gin.H{
+        "job_id": jobID,
+        "status": services.JobStatusQueued,
+    })
+}
+
+// HandleGetJob 查询任务状态
+func HandleGetJob(c *gin.Context) {
+    status, err := services.GetJobStatus(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, status)
+}
+
+// HandleGetJobResult 分页获取任务结果
+func HandleGetJobResult(c *gin.Context) {
+    page, pageSize := parsePagination(c)
+
+    result, err := services.GetJobResult(c.Param("id"), page, pageSize)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrJobNotFound):
+            c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+        case errors.Is(err, services.ErrJobNotDone):
+            c.JSON(http.StatusConflict, gin.H{"error": "Job has no result yet"})
+        default:
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job result"})
+        }
+        return
+    }
+
+    c.JSON(http.StatusOK, result)
+}
+
+// HandleCancelJob 取消任务
+func HandleCancelJob(c *gin.Context) {
+    jobID := c.Param("id")
+    if err := services.CancelJob(jobID); err != nil {
+        switch {
+        case errors.Is(err, services.ErrJobNotFound):
+            c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+        case errors.Is(err, services.ErrJobFinished):
+            c.JSON(http.StatusConflict, gin.H{"error": "Job has already finished"})
+        default:
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+        }
+        return
+    }
+    log.Printf("Cancelled job %s", jobID)
+
+    c.JSON(http.StatusOK, gin.H{
+        "job_id": jobID,
+        "status": services.JobStatusCancelled,
+    })
+}
+
+// parsePagination 解析 page 和 page_size 参数
+func parsePagination(c *gin.Context) (int, int) {
+    page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+    if err != nil || page < 1 {
+        page = 1
+    }
+
+    pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+    if err != nil || pageSize < 1 {
+        pageSize = defaultPageSize
+    }
+    if pageSize > maxPageSize {
+        pageSize = maxPageSize
+    }
+
+    return page, pageSize
+}
+
+// pageBounds 根据分页参数计算切片的起止位置
+func pageBounds(total, page, pageSize int) (int, int) {
+    start := (page - 1) * pageSize
+    if start > total {
+        start = total
+    }
+    end := start + pageSize
+    if end > total {
+        end = total
+    }
+    return start, end
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "path/filepath"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// 导入文件的大小上限
+const maxMetadataImportSize = 10 << 20
+
+// HandleListMetadata 列出所有表和字段的补充元数据
+func HandleListMetadata(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "tables": services.ListMetadata(),
+    })
+}
+
+// HandleGetTableMetadata 获取表的补充元数据
+func HandleGetTableMetadata(c *gin.Context) {
+    meta, err := services.GetTableMetadata(c.Param("name"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Metadata not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, meta)
+}
+
+// HandleSetTableMetadata 设置表的描述、别名、废弃标记和使用提示
+func HandleSetTableMetadata(c *gin.Context) {
+    var req models.MetadataRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    tableName := c.Param("name")
+    if err := services.SetTableMetadata(tableName, metadataEntryFromRequest(req)); err != nil {
+        log.Printf("Error saving metadata for %s: %s", tableName, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save metadata"})
+        return
+    }
+    log.Printf("Updated metadata for table %s", tableName)
+
+    meta, _ := services.GetTableMetadata(tableName)
+    c.JSON(http.StatusOK, meta)
+}
+
+// HandleSetColumnMetadata 设置字段的描述、别名、废弃标记和使用提示
+func HandleSetColumnMetadata(c *gin.Context) {
+    var req models.MetadataRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    tableName, column := c.Param("name"), c.Param("column")
+    if err := services.SetColumnMetadata(tableName, column, metadataEntryFromRequest(req)); err != nil {
+        log.Printf("Error saving metadata for %s.%s: %s", tableName, column, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save metadata"})
+        return
+    }
+    log.Printf("Updated metadata for column %s.%s", tableName, column)
+
+    meta, _ := services.GetTableMetadata(tableName)
+    c.JSON(http.StatusOK, meta)
+}
+
+// HandleDeleteTableMetadata 删除表及其字段的补充元数据
+func HandleDeleteTableMetadata(c *gin.Context) {
+    tableName := c.Param("name")
+    if err := services.DeleteTableMetadata(tableName); err != nil {
+        respondMetadataError(c, err)
+        return
+    }
+    log.Printf("Deleted metadata for table %s", tableName)
+
+    c.JSON(http.StatusOK, gin.H{"table": tableName})
+}
+
+// HandleDeleteColumnMetadata 删除字段的补充元数据
+func HandleDeleteColumnMetadata(c *gin.Context) {
+    tableName, column := c.Param("name"), c.Param("column")
+    if err := services.DeleteColumnMetadata(tableName, column); err != nil {
+        respondMetadataError(c, err)
+        return
+    }
+    log.Printf("Deleted metadata for column %s.%s", tableName, column)
+
+    c.JSON(http.StatusOK, gin.H{"table": tableName, "column": column})
+}
+
+// HandleImportMetadata 从 CSV 或 YAML 导入补充元数据，支持 multipart 上传（字段名 file）或直接提交文件内容，
+// 格式由 format 参数或文件扩展名决定
+func HandleImportMetadata(c *gin.Context) {
+    format := strings.ToLower(c.Query("format"))
+
+    var reader io.Reader = c.Request.Body
+    if file, header, err := c.Request.FormFile("file"); err == nil {
+        defer file.Close()
+        reader = file
+        if format == "" {
+            format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+        }
+    }
+    data, err := io.ReadAll(io.LimitReader(reader, maxMetadataImportSize))
+    if err != nil {
+        log.Printf("Error reading metadata import: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    var imported map[string]*services.TableMetadata
+    switch format {
+    case "csv":
+        imported, err = services.ParseMetadataCSV(data)
+    case "yaml", "yml":
+        imported, err = services.ParseMetadataYAML(data)
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, use csv or yaml"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    count, err := services.ImportMetadata(imported)
+    if err != nil {
+        log.Printf("Error importing metadata: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import metadata"})
+        return
+    }
+    log.Printf("Imported metadata for %d tables and columns", count)
+
+    c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+func metadataEntryFromRequest(req models.MetadataRequest) services.MetadataEntry {
+    return services.MetadataEntry{
+        Description: strings.TrimSpace(req.Description),
+        Aliases:     req.Aliases,
+        Tags:        req.Tags,
+        Deprecated:  req.Deprecated,
+        DoNotUse:    strings.TrimSpace(req.DoNotUse),
+    }
+}
+
+func respondMetadataError(c *gin.Context, err error) {
+    if errors.Is(err, services.ErrMetadataNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Metadata not found"})
+        return
+    }
+    log.Printf("Error saving metadata: %s", err.Error())
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save metadata"})
+}
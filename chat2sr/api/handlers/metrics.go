@@ -0,0 +1,63 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+)
+
+// HandleListMetrics 列出指标层中定义的指标和维度
+func HandleListMetrics(c *gin.Context) {
+    layer, err := services.CurrentSemanticLayer()
+    if err != nil {
+        if errors.Is(err, services.ErrSemanticLayerMissing) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Semantic layer is not configured"})
+            return
+        }
+        log.Printf("Error loading semantic layer: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load semantic layer"})
+        return
+    }
+
+    c.JSON(http.StatusOK, layer)
+}
+
+// HandleCompileMetricQuery 将指标查询编译为SQL，不经过模型
+func HandleCompileMetricQuery(c *gin.Context) {
+    var req services.MetricQuery
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    layer, err := services.CurrentSemanticLayer()
+    if err != nil {
+        if errors.Is(err, services.ErrSemanticLayerMissing) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Semantic layer is not configured"})
+            return
+        }
+        log.Printf("Error loading semantic layer: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load semantic layer"})
+        return
+    }
+
+    sql, tables, err := layer.CompileMetricQuery(req)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := services.ValidateSQL(sql); err != nil {
+        log.Printf("Compiled metric SQL failed validation: %s", err.Error())
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "sql": sql})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "sql":          sql,
+        "tables":       tables,
+        "metric_query": req,
+    })
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+    "errors"
     "log"
     "net/http"
     "strings"
@@ -20,29 +21,103 @@ func HandleNLQuery(c *gin.Context) {
         return
     }
 
+    // 回答追问时不重新检索和选表
+    if req.ClarificationID != "" {
+        handleClarificationAnswer(c, req)
+        return
+    }
+
     if strings.TrimSpace(req.UserInput) == "" {
         log.Printf("Empty user input received")
         c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide a query description"})
         return
     }
     log.Printf("Received user input: %s", req.UserInput)
+
+    // 指定 session_id 时在已有对话中追问，否则新建对话
+    session, err := loadOrCreateSession(req)
+    if err != nil {
+        respondSessionError(c, err)
+        return
+    }
+    lastTurn := session.LastTurn()
+
+    // 先判断问题类型，元数据、术语定义和不支持的请求不进入选表和生成SQL的流程
+    intent := services.ClassifyIntent(req.UserInput, lastTurn != nil)
+    log.Printf("Routed question to %s (%s)", intent.Route, intent.Reason)
+    if intent.Route != services.IntentData {
+        answer, err := services.AnswerIntent(intent.Route, req.UserInput)
+        if err != nil {
+            log.Printf("Error answering %s question: %s", intent.Route, err.Error())
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer question"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "route":      answer.Route,
+            "reason":     intent.Reason,
+            "answer":     answer.Answer,
+            "tables":     answer.Tables,
+            "glossary":   answer.Glossary,
+            "metrics":    answer.Metrics,
+            "session_id": session.ID,
+        })
+        return
+    }
+
+    // 0. 问题涉及已定义的指标时，优先映射为指标查询并确定性地编译SQL，追问需要结合上一轮SQL，不走指标层
+    if lastTurn == nil {
+        metricAnswer, err := services.AnswerWithMetrics(req.UserInput)
+        if err != nil {
+            log.Printf("Error mapping question to metrics, falling back to SQL generation: %s", err.Error())
+        } else if metricAnswer != nil {
+            log.Printf("Answered with governed metrics: %s", metricAnswer.SQL)
+            // 指标层编译的SQL与模型生成的SQL经过同样的校验
+            if err := services.ValidateSQL(metricAnswer.SQL); err != nil {
+                log.Printf("Compiled metric SQL failed validation: %s", err.Error())
+                recordGenerated(session, req.UserInput, metricAnswer.SQL, metricAnswer.Tables, "semantic_layer", err)
+                c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "sql": metricAnswer.SQL})
+                return
+            }
+            recordSessionTurn(session.ID, req.UserInput, metricAnswer.SQL, metricAnswer.Tables)
+            historyID := recordGenerated(session, req.UserInput, metricAnswer.SQL, metricAnswer.Tables, "semantic_layer", nil)
+            c.JSON(http.StatusOK, gin.H{
+                "sql":          metricAnswer.SQL,
+                "tables":       metricAnswer.Tables,
+                "metric_query": metricAnswer.Query,
+                "source":       "semantic_layer",
+                "route":        services.IntentData,
+                "session_id":   session.ID,
+                "history_id":   historyID,
+            })
+            return
+        }
+    }
     
     // 1. 获取所有表及其注释
-    allTables, err := services.GetAllTablesWithComments()
+    allTables, err := services.CatalogTables()
     if err != nil {
         log.Printf("Error getting tables: %s", err.Error())
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get database tables"})
         return
     }
     
-    // 2. 使用关键词匹配筛选可能相关的表
-    filteredTables := services.FilterTablesByKeywords(allTables, req.UserInput)
-    log.Printf("Filtered tables by keywords: %v", filteredTables)
+    // 2. 按 BM25 和语义相似度检索可能相关的表，追问时结合上一轮的问题检索，并保留上一轮使用的表
+    retrievalQuestion := req.UserInput
+    if lastTurn != nil {
+        retrievalQuestion = lastTurn.Question + " " + req.UserInput
+    }
+    retrieval := services.RetrieveTables(allTables, retrievalQuestion)
+    filteredTables := retrieval.Tables
+    if lastTurn != nil {
+        filteredTables = appendPreviousTables(filteredTables, allTables, lastTurn.Tables)
+    }
+    log.Printf("Retrieved candidate tables: %v", filteredTables)
     
     // 3. 获取筛选后表的详细结构信息
     var tablesInfo strings.Builder
+    schemaBudget := services.SchemaTokenBudget(len(filteredTables))
     for _, table := range filteredTables {
-        columns, err := services.GetTableSchemaWithComments(table.Name)
+        schema, err := services.CatalogTable(table.Name)
         if err != nil {
             log.Printf("Error getting schema for table %s: %s", table.Name, err.Error())
             continue
@@ -52,15 +127,19 @@ func HandleNLQuery(c *gin.Context) {
         if table.Comment != "" {
             tablesInfo.WriteString(fmt.Sprintf(" (说明: %s)", table.Comment))
         }
-        tablesInfo.WriteString("\n字段列表:\n")
+        tablesInfo.WriteString("\n")
+        tablesInfo.WriteString(services.FormatTableDetails(schema.Details))
+        tablesInfo.WriteString("字段列表:\n")
         
-        for _, col := range columns {
-            fieldDesc := fmt.Sprintf("- %s (%s)", col["name"], col["type"])
-            if col["comment"] != "" {
-                fieldDesc += fmt.Sprintf(" 说明: %s", col["comment"])
+        promptColumns := services.SelectPromptColumns(schema, retrievalQuestion, schemaBudget)
+        for _, col := range promptColumns.Columns {
+            fieldDesc := fmt.Sprintf("- %s (%s)", col.Name, col.Type)
+            if col.Comment != "" {
+                fieldDesc += fmt.Sprintf(" 说明: %s", col.Comment)
             }
             tablesInfo.WriteString(fieldDesc + "\n")
         }
+        tablesInfo.WriteString(services.FormatOmittedColumns(promptColumns.Omitted))
         tablesInfo.WriteString("\n")
     }
 
@@ -74,8 +153,8 @@ func HandleNLQuery(c *gin.Context) {
 
 数据库表结构:
 %s
-
-用户需求: %s`, tablesInfo.String(), req.UserInput)
+%s
+用户需求: %s`, tablesInfo.String(), services.FormatSessionHistory(session), req.UserInput)
 
     tablesResponse, err := services.ProcessQuery(llmPrompt)
     if err != nil {
@@ -84,25 +163,128 @@ func HandleNLQuery(c *gin.Context) {
         return
     }
     log.Printf("LLM identified tables: %s", tablesResponse)
+
+    selectedTables := strings.Split(strings.ReplaceAll(tablesResponse, " ", ""), ",")
+
+    // 5. 候选表难以区分、时间范围含糊或指标没有定义时，先请用户确认，回答后从这里继续
+    if questions := services.DetectAmbiguity(retrievalQuestion, retrieval, filteredTables, selectedTables); len(questions) > 0 {
+        pending, err := services.CreateClarification(services.Clarification{
+            SessionID: session.ID,
+            Question:  req.UserInput,
+            Tables:    selectedTables,
+            Retrieval: retrieval,
+            Questions: questions,
+        })
+        if err != nil {
+            log.Printf("Error saving clarification: %s", err.Error())
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save clarification"})
+            return
+        }
+        log.Printf("Asking for clarification %s: %+v", pending.ID, questions)
+        c.JSON(http.StatusOK, gin.H{
+            "clarification_id": pending.ID,
+            "questions":        questions,
+            "source":           "clarification",
+            "route":            services.IntentData,
+            "retrieval":        retrieval,
+            "session_id":       session.ID,
+        })
+        return
+    }
+
+    respondWithGeneratedSQL(c, session, req.UserInput, selectedTables, retrieval)
+}
+
+// handleClarificationAnswer 用户回答追问后，沿用保存的选表结果继续生成SQL
+func handleClarificationAnswer(c *gin.Context, req models.QueryRequest) {
+    resolved, err := services.ResolveClarification(req.ClarificationID, req.Answers)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrClarificationNotFound):
+            c.JSON(http.StatusNotFound, gin.H{"error": "Clarification not found or expired"})
+        case errors.Is(err, services.ErrClarificationInvalid):
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        default:
+            log.Printf("Error resolving clarification: %s", err.Error())
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve clarification"})
+        }
+        return
+    }
+    log.Printf("Resuming clarified question: %s", resolved.Question)
+
+    session, err := services.GetSession(resolved.SessionID)
+    if err != nil {
+        respondSessionError(c, err)
+        return
+    }
+    respondWithGeneratedSQL(c, session, resolved.Question, resolved.Tables, resolved.Retrieval)
+}
+
+// respondWithGeneratedSQL 补充桥接表后生成SQL，记录到对话中并返回
+func respondWithGeneratedSQL(c *gin.Context, session *services.Session, question string, selectedTables []string, retrieval *services.TableRetrieval) {
+    // 按关联关系图补充选中表之间的桥接表
+    selectedTables, joins := services.CurrentJoinGraph().ConnectTables(selectedTables)
+    log.Printf("Tables after adding join bridges: %v", selectedTables)
     
-    // 5. 将用户输入和识别的表信息一起传递给SQL生成服务
-    enrichedInput := fmt.Sprintf("用户需求: %s\n需要使用的表: %s", req.UserInput, tablesResponse)
+    // 将用户输入和识别的表信息一起传递给SQL生成服务
+    enrichedInput := fmt.Sprintf("用户需求: %s\n需要使用的表: %s", question, strings.Join(selectedTables, ","))
+    if history := services.FormatSessionHistory(session); history != "" {
+        enrichedInput += "\n" + history
+    }
     log.Printf("Generating SQL with enriched input: %s", enrichedInput)
     
     sqlQuery, err := services.GenerateSQL(enrichedInput)
     if err != nil {
         log.Printf("Error generating SQL: %s", err.Error())
+        recordGenerated(session, question, "", selectedTables, "llm", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate SQL"})
         return
     }
     log.Printf("Generated SQL query: %s", sqlQuery)
+    if err := services.ValidateSQL(sqlQuery); err != nil {
+        log.Printf("Generated SQL failed validation: %s", err.Error())
+        recordGenerated(session, question, sqlQuery, selectedTables, "llm", err)
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "sql": sqlQuery})
+        return
+    }
+    recordSessionTurn(session.ID, question, sqlQuery, selectedTables)
+    historyID := recordGenerated(session, question, sqlQuery, selectedTables, "llm", nil)
 
     response := gin.H{
         "sql": sqlQuery,
-        "tables": strings.Split(strings.ReplaceAll(tablesResponse, " ", ""), ","),
+        "tables": selectedTables,
+        "joins": joins,
+        "source": "llm",
+        "route": services.IntentData,
+        "retrieval": retrieval,
+        "session_id": session.ID,
+        "follow_up": session.LastTurn() != nil,
+        "history_id": historyID,
     }
     
     log.Printf("Preparing response data: %+v", response)
     c.JSON(http.StatusOK, response)
     log.Printf("Response sent with status 200 and data: %+v", response)
-}
\ No newline at end of file
+}
+
+// recordGenerated 记录生成的SQL，执行时带上返回的 history_id 即可补充执行结果
+func recordGenerated(session *services.Session, question, sqlQuery string, tables []string, source string, genErr error) string {
+    entry := services.HistoryEntry{
+        User:      session.User,
+        SessionID: session.ID,
+        Question:  question,
+        SQL:       sqlQuery,
+        Tables:    tables,
+        Source:    source,
+        Status:    services.HistoryStatusGenerated,
+    }
+    if genErr != nil {
+        entry.Status = services.HistoryStatusFailed
+        entry.Error = genErr.Error()
+    }
+    id, err := services.RecordHistory(entry)
+    if err != nil {
+        log.Printf("Error recording history: %s", err.Error())
+    }
+    return id
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+)
+
+// HandleGetResult 分页获取保存的查询结果，支持按列排序
+func HandleGetResult(c *gin.Context) {
+    page, pageSize := parsePagination(c)
+    sortBy := c.Query("sort_by")
+    desc := strings.EqualFold(c.Query("order"), "desc")
+
+    result, err := services.GetResultPage(c.Param("id"), page, pageSize, sortBy, desc)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrResultNotFound):
+            c.JSON(http.StatusNotFound, gin.H{"error": "Result not found or expired"})
+        case errors.Is(err, services.ErrResultSortInvalid):
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        default:
+            log.Printf("Error reading result %s: %s", c.Param("id"), err.Error())
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read query result"})
+        }
+        return
+    }
+
+    c.JSON(http.StatusOK, result)
+}
+
+// HandleDownloadResult 下载保存的查询结果，format 支持 csv 和 excel
+func HandleDownloadResult(c *gin.Context) {
+    result, err := services.LoadResult(c.Param("id"))
+    if err != nil {
+        if errors.Is(err, services.ErrResultNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Result not found or expired"})
+            return
+        }
+        log.Printf("Error reading result %s: %s", c.Param("id"), err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read query result"})
+        return
+    }
+
+    switch c.DefaultQuery("format", "csv") {
+    case "csv":
+        c.Header("Content-Type", "text/csv; charset=utf-8")
+        c.Header("Content-Disposition", `attachment; filename="query_results.csv"`)
+        err = services.WriteResultCSV(result, c.Writer)
+    case "excel":
+        c.Header("Content-Type", "application/vnd.ms-excel; charset=utf-8")
+        c.Header("Content-Disposition", `attachment; filename="query_results.xls"`)
+        err = services.WriteResultExcel(result, c.Writer)
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, use csv or excel"})
+        return
+    }
+
+    if err != nil {
+        log.Printf("Error writing result %s: %s", result.ID, err.Error())
+    }
+}
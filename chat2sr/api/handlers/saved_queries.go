@@ -0,0 +1,221 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strconv"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleListSavedQueries 列出保存的查询，支持 q 参数检索
+func HandleListSavedQueries(c *gin.Context) {
+    list := services.ListSavedQueries(c.Query("q"))
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(list), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "saved_queries": list[start:end],
+        "total":         len(list),
+        "page":          page,
+        "page_size":     pageSize,
+    })
+}
+
+// HandleGetSavedQuery 获取保存的查询
+func HandleGetSavedQuery(c *gin.Context) {
+    query, err := services.GetSavedQuery(c.Param("id"))
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, query)
+}
+
+// HandleCreateSavedQuery 保存参数化查询，SQL中用 {{name}} 表示参数
+func HandleCreateSavedQuery(c *gin.Context) {
+    var req models.SavedQueryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    query, err := services.CreateSavedQuery(savedQueryVersionFromRequest(req), req.User, req.Tags)
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+    log.Printf("Created saved query %s: %s", query.ID, query.Name)
+
+    c.JSON(http.StatusCreated, query)
+}
+
+// HandleUpdateSavedQuery 修改保存的查询，生成新版本
+func HandleUpdateSavedQuery(c *gin.Context) {
+    var req models.SavedQueryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Printf("Invalid request: %s", err.Error())
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    query, err := services.UpdateSavedQuery(c.Param("id"), savedQueryVersionFromRequest(req), req.User, req.Tags)
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+    log.Printf("Updated saved query %s to version %d", query.ID, query.Version)
+
+    c.JSON(http.StatusOK, query)
+}
+
+// HandleDeleteSavedQuery 删除保存的查询
+func HandleDeleteSavedQuery(c *gin.Context) {
+    if err := services.DeleteSavedQuery(c.Param("id")); err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// HandleListSavedQueryVersions 列出保存的查询的所有版本
+func HandleListSavedQueryVersions(c *gin.Context) {
+    versions, err := services.SavedQueryVersions(c.Param("id"))
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// HandleDiffSavedQuery 比较两个版本，from 默认为上一版本，to 默认为当前版本
+func HandleDiffSavedQuery(c *gin.Context) {
+    query, err := services.GetSavedQuery(c.Param("id"))
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+    to, err := strconv.Atoi(c.DefaultQuery("to", strconv.Itoa(query.Version)))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+        return
+    }
+    from, err := strconv.Atoi(c.DefaultQuery("from", strconv.Itoa(to-1)))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+        return
+    }
+
+    diff, err := services.DiffSavedQuery(query.ID, from, to)
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "from": from,
+        "to":   to,
+        "diff": diff,
+    })
+}
+
+// HandleSavedQueryParamOptions 获取枚举参数的可选值
+func HandleSavedQueryParamOptions(c *gin.Context) {
+    query, err := services.GetSavedQuery(c.Param("id"))
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+    for _, param := range query.Parameters {
+        if param.Name != c.Param("name") {
+            continue
+        }
+        options, err := services.ParameterOptions(param)
+        if err != nil {
+            respondSavedQueryError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"name": param.Name, "type": param.Type, "options": options})
+        return
+    }
+
+    c.JSON(http.StatusNotFound, gin.H{"error": "Parameter not found"})
+}
+
+// HandleRunSavedQuery 绑定参数后执行保存的查询，参数通过占位符传给数据库，不拼接到SQL中
+func HandleRunSavedQuery(c *gin.Context) {
+    var req models.RunSavedQueryRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    query, err := services.GetSavedQuery(c.Param("id"))
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+    boundSQL, args, err := services.BindSavedQuery(query, req.Params)
+    if err != nil {
+        respondSavedQueryError(c, err)
+        return
+    }
+
+    entry := services.HistoryEntry{
+        User:     req.User,
+        Question: query.Name,
+        SQL:      boundSQL,
+        Args:     args,
+        Tables:   services.ExtractSQLTables(boundSQL),
+        Source:   "saved_query:" + query.ID,
+    }
+    executeAndRespond(c, entry, "")
+}
+
+func savedQueryVersionFromRequest(req models.SavedQueryRequest) services.SavedQueryVersion {
+    version := services.SavedQueryVersion{
+        Name:        req.Name,
+        Description: req.Description,
+        SQL:         req.SQL,
+    }
+    for _, param := range req.Parameters {
+        version.Parameters = append(version.Parameters, services.QueryParameter{
+            Name:     param.Name,
+            Type:     param.Type,
+            Label:    param.Label,
+            Default:  param.Default,
+            Required: param.Required,
+            Options:  param.Options,
+            Table:    param.Table,
+            Column:   param.Column,
+        })
+    }
+    return version
+}
+
+func respondSavedQueryError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrSavedQueryNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Saved query not found"})
+    case errors.Is(err, services.ErrVersionNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+    case errors.Is(err, services.ErrTableNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Table not found"})
+    case errors.Is(err, services.ErrTableNotAllowed):
+        c.JSON(http.StatusForbidden, gin.H{"error": "Table is deprecated"})
+    case errors.Is(err, services.ErrSavedQueryInvalid), errors.Is(err, services.ErrQueryParamInvalid):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    default:
+        log.Printf("Error handling saved query: %s", err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to handle saved query"})
+    }
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "log"
+    "net/http"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+)
+
+// HandleSchemaRefresh 强制重新加载数据源的表结构缓存，并返回本次检测到的变化
+func HandleSchemaRefresh(c *gin.Context) {
+    catalog, err := services.GetCatalog(c.Query("datasource"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Datasource not found"})
+        return
+    }
+
+    changes, err := catalog.Refresh()
+    if err != nil {
+        log.Printf("Error refreshing schema for %s: %s", catalog.Datasource, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh schema"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "datasource": catalog.Datasource,
+        "tables":     len(catalog.Tables()),
+        "loaded_at":  catalog.LoadedAt(),
+        "changes":    changes,
+    })
+}
+
+// HandleListDatasources 列出数据源
+func HandleListDatasources(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "datasources": services.ListDatasources(),
+    })
+}
+
+// HandleListTables 分页列出数据源中的表及注释
+func HandleListTables(c *gin.Context) {
+    catalog, err := services.GetCatalog(c.Query("datasource"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Datasource not found"})
+        return
+    }
+
+    tables := catalog.Tables()
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(tables), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "datasource": catalog.Datasource,
+        "tables":     tables[start:end],
+        "total":      len(tables),
+        "page":       page,
+        "page_size":  pageSize,
+    })
+}
+
+// HandleListColumns 分页列出表的字段、类型及注释
+func HandleListColumns(c *gin.Context) {
+    catalog, err := services.GetCatalog(c.Query("datasource"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Datasource not found"})
+        return
+    }
+
+    table, ok := catalog.Table(c.Param("name"))
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Table not found"})
+        return
+    }
+
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(table.Columns), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "datasource": catalog.Datasource,
+        "table":      table.Name,
+        "comment":    table.Comment,
+        "details":    table.Details,
+        "columns":    table.Columns[start:end],
+        "total":      len(table.Columns),
+        "page":       page,
+        "page_size":  pageSize,
+    })
+}
+
+// HandleSearchSchema 在表名、字段名和注释中全文搜索
+func HandleSearchSchema(c *gin.Context) {
+    query := strings.TrimSpace(c.Query("q"))
+    if query == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide a search keyword"})
+        return
+    }
+
+    catalog, err := services.GetCatalog(c.Query("datasource"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Datasource not found"})
+        return
+    }
+
+    hits := catalog.Search(query)
+    page, pageSize := parsePagination(c)
+    start, end := pageBounds(len(hits), page, pageSize)
+
+    c.JSON(http.StatusOK, gin.H{
+        "datasource": catalog.Datasource,
+        "results":    hits[start:end],
+        "total":      len(hits),
+        "page":       page,
+        "page_size":  pageSize,
+    })
+}
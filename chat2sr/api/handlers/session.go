@@ -0,0 +1,99 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+    "chat2sr/api/models"
+)
+
+// HandleCreateSession 新建多轮对话
+func HandleCreateSession(c *gin.Context) {
+    var req models.SessionRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Printf("Invalid request: %s", err.Error())
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    session, err := services.CreateSession(req.User)
+    if err != nil {
+        respondSessionError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, session)
+}
+
+// HandleGetSession 获取对话及其历史
+func HandleGetSession(c *gin.Context) {
+    session, err := services.GetSession(c.Param("id"))
+    if err != nil {
+        respondSessionError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, session)
+}
+
+// HandleDeleteSession 删除对话
+func HandleDeleteSession(c *gin.Context) {
+    if err := services.DeleteSession(c.Param("id")); err != nil {
+        respondSessionError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// loadOrCreateSession 读取请求中指定的对话，未指定时新建
+func loadOrCreateSession(req models.QueryRequest) (*services.Session, error) {
+    if req.SessionID == "" {
+        return services.CreateSession(req.User)
+    }
+    return services.GetSession(req.SessionID)
+}
+
+// recordSessionTurn 记录本轮问答，失败只影响后续追问，不影响本次响应
+func recordSessionTurn(sessionID, question, sqlQuery string, tables []string) {
+    turn := services.SessionTurn{Question: question, SQL: sqlQuery, Tables: tables}
+    if _, err := services.AppendSessionTurn(sessionID, turn); err != nil {
+        log.Printf("Error recording session %s: %s", sessionID, err.Error())
+    }
+}
+
+// appendPreviousTables 将上一轮使用的表加入候选表
+func appendPreviousTables(candidates, allTables []services.TableInfo, previous []string) []services.TableInfo {
+    for _, name := range previous {
+        found := false
+        for _, table := range candidates {
+            if table.Name == name {
+                found = true
+                break
+            }
+        }
+        if found {
+            continue
+        }
+        for _, table := range allTables {
+            if table.Name == name {
+                candidates = append(candidates, table)
+                break
+            }
+        }
+    }
+    return candidates
+}
+
+func respondSessionError(c *gin.Context, err error) {
+    if errors.Is(err, services.ErrSessionNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+        return
+    }
+    log.Printf("Error accessing session: %s", err.Error())
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access session"})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "github.com/gin-gonic/gin"
+    "chat2sr/services"
+)
+
+const (
+    defaultPreviewRows = 20
+    maxPreviewRows     = 1000
+)
+
+// HandleTablePreview 预览表的前N行数据
+func HandleTablePreview(c *gin.Context) {
+    tableName := c.Param("name")
+
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPreviewRows)))
+    if err != nil || limit < 1 {
+        limit = defaultPreviewRows
+    }
+    if limit > maxPreviewRows {
+        limit = maxPreviewRows
+    }
+
+    columns, results, err := services.PreviewTable(c.Request.Context(), tableName, limit)
+    if err != nil {
+        if respondTableError(c, err) {
+            return
+        }
+        log.Printf("Error previewing table %s: %s", tableName, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview table"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "table":   tableName,
+        "columns": columns,
+        "results": results,
+    })
+}
+
+// HandleTableProfile 统计表中字段的空值比例、基数、最小最大值和高频取值
+func HandleTableProfile(c *gin.Context) {
+    tableName := c.Param("name")
+
+    var columns []string
+    for _, col := range strings.Split(c.Query("columns"), ",") {
+        if col = strings.TrimSpace(col); col != "" {
+            columns = append(columns, col)
+        }
+    }
+
+    profile, err := services.ProfileTable(c.Request.Context(), tableName, columns)
+    if err != nil {
+        if respondTableError(c, err) {
+            return
+        }
+        log.Printf("Error profiling table %s: %s", tableName, err.Error())
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to profile table"})
+        return
+    }
+
+    c.JSON(http.StatusOK, profile)
+}
+
+// respondTableError 处理表不存在、不允许访问和字段不存在的错误，返回是否已响应
+func respondTableError(c *gin.Context, err error) bool {
+    switch {
+    case errors.Is(err, services.ErrTableNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Table not found"})
+    case errors.Is(err, services.ErrTableNotAllowed):
+        c.JSON(http.StatusForbidden, gin.H{"error": "Table is deprecated"})
+    case errors.Is(err, services.ErrColumnsNotFound):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    default:
+        return false
+    }
+    return true
+}
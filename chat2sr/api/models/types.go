@@ -11,11 +11,110 @@ type Config struct {
 }
 
 type QueryRequest struct {
-    UserInput string `json:"user_input"`
+    UserInput       string            `json:"user_input"`
+    SessionID       string            `json:"session_id"`
+    User            string            `json:"user"`
+    ClarificationID string            `json:"clarification_id"`
+    Answers         map[string]string `json:"answers"`
 }
 
 type ExecuteRequest struct {
-    SQL string `json:"sql"`
+    SQL       string `json:"sql"`
+    Query     string `json:"query"`
+    SessionID string `json:"session_id"`
+    HistoryID string `json:"history_id"`
+    User      string `json:"user"`
+}
+
+type JobSubmitRequest struct {
+    SQL  string `json:"sql"`
+    User string `json:"user"`
+}
+
+type GlossaryEntryRequest struct {
+    Term        string   `json:"term"`
+    Synonyms    []string `json:"synonyms"`
+    Description string   `json:"description"`
+    Tables      []string `json:"tables"`
+    Columns     []string `json:"columns"`
+    Expression  string   `json:"expression"`
+    Filter      string   `json:"filter"`
+}
+
+type ExampleRequest struct {
+    Question string `json:"question"`
+    SQL      string `json:"sql"`
+    Notes    string `json:"notes"`
+}
+
+type SaveExampleRequest struct {
+    Question string `json:"question"`
+    Notes    string `json:"notes"`
+}
+
+type MetadataRequest struct {
+    Description string   `json:"description"`
+    Aliases     []string `json:"aliases"`
+    Tags        []string `json:"tags"`
+    Deprecated  bool     `json:"deprecated"`
+    DoNotUse    string   `json:"do_not_use"`
+}
+
+type AutoDocRequest struct {
+    Tables    []string `json:"tables"`
+    MaxTables int      `json:"max_tables"`
+}
+
+type ReviewProposalRequest struct {
+    Description string `json:"description"`
+    Reviewer    string `json:"reviewer"`
+}
+
+type DbtImportRequest struct {
+    Path string `json:"path"`
+}
+
+type SessionRequest struct {
+    User string `json:"user"`
+}
+
+type QueryParameterRequest struct {
+    Name     string   `json:"name"`
+    Type     string   `json:"type"`
+    Label    string   `json:"label"`
+    Default  string   `json:"default"`
+    Required bool     `json:"required"`
+    Options  []string `json:"options"`
+    Table    string   `json:"table"`
+    Column   string   `json:"column"`
+}
+
+type SavedQueryRequest struct {
+    Name        string                  `json:"name"`
+    Description string                  `json:"description"`
+    SQL         string                  `json:"sql"`
+    Parameters  []QueryParameterRequest `json:"parameters"`
+    Tags        []string                `json:"tags"`
+    User        string                  `json:"user"`
+}
+
+type RunSavedQueryRequest struct {
+    Params map[string]string `json:"params"`
+    User   string            `json:"user"`
+}
+
+type ExplainSQLRequest struct {
+    SQL       string `json:"sql"`
+    HistoryID string `json:"history_id"`
+    Language  string `json:"language"`
+}
+
+type EditSQLRequest struct {
+    SQL         string `json:"sql"`
+    Instruction string `json:"instruction"`
+    HistoryID   string `json:"history_id"`
+    SessionID   string `json:"session_id"`
+    User        string `json:"user"`
 }
 
 type DeepSeekRequest struct {
@@ -6,6 +6,7 @@ import (
     "chat2sr/config"
     "chat2sr/logs"
     "chat2sr/routers"
+    "chat2sr/services"
 )
 
 func main() {
@@ -15,6 +16,21 @@ func main() {
     // 初始化配置
     config.Init()
 
+    // 启动异步查询worker
+    services.StartJobWorkers()
+
+    // 定期清理过期的查询结果
+    services.StartResultCleanup()
+
+    // 定期清理过期的多轮对话
+    services.StartSessionCleanup()
+
+    // 后台加载表结构缓存
+    services.StartSchemaCatalog()
+
+    // 后台采集字段取值示例
+    services.StartValueProfiler()
+
     // 设置路由
     router := routers.SetupRouter()
 
@@ -0,0 +1,40 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "chat2sr/config"
+    "chat2sr/logs"
+    "chat2sr/services"
+)
+
+// 批量为缺少注释的表和字段生成待审核的描述：
+// go run ./cmd/autodoc -tables ods_order,ods_user -max 20
+func main() {
+    tables := flag.String("tables", "", "逗号分隔的表名，为空时处理所有表")
+    maxTables := flag.Int("max", 0, "最多处理的表数，0 表示不限制")
+    flag.Parse()
+
+    logs.Init()
+    config.Init()
+
+    var names []string
+    for _, name := range strings.Split(*tables, ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            names = append(names, name)
+        }
+    }
+
+    summary, err := services.RunAutoDoc(names, *maxTables)
+    if err != nil {
+        log.Printf("Error running auto documentation: %s", err.Error())
+        os.Exit(1)
+    }
+    fmt.Printf("Generated %d proposals for %d tables\n", summary.Proposals, summary.Tables)
+    if len(summary.Failed) > 0 {
+        fmt.Printf("Failed tables: %s\n", strings.Join(summary.Failed, ", "))
+    }
+}
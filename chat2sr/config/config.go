@@ -3,6 +3,8 @@ package config
 import (
     "os"
     "log"
+    "strconv"
+    "time"
     "github.com/joho/godotenv"
 )
 
@@ -15,6 +17,82 @@ type Config struct {
 	DBPassword            string
 	DBName                string
 	ServerPort            string
+	DataDir               string
+
+	// 异步查询任务
+	JobWorkers            int
+	JobQueueSize          int
+
+	// 服务端结果存储
+	ResultRetention       time.Duration
+
+	// 表结构缓存
+	SchemaCacheTTL        time.Duration
+	TableDetailTTL        time.Duration
+	TableDetailWorkers    int
+
+	// 字段取值采集
+	ProfileEnabled        bool
+	ProfileInterval       time.Duration
+	ProfileColumns        string
+	ProfileTopN           int
+	ProfileMaxDistinct    int
+	ProfileSampleRows     int
+	PromptSampleTokens    int
+
+	// 提示词字段裁剪
+	PromptSchemaTokens    int
+	PromptMaxColumns      int
+
+	// 表检索
+	EmbeddingProvider     string
+	EmbeddingAPIURL       string
+	EmbeddingAPIKey       string
+	EmbeddingModel        string
+	EmbeddingDim          int
+	RetrievalTopK         int
+	RetrievalMinScore     float64
+	RetrievalFusedTopK    int
+
+	// BM25 检索
+	BM25TopK              int
+	BM25MinScore          float64
+	BM25WeightTableName   float64
+	BM25WeightTableComment float64
+	BM25WeightColumnName  float64
+	BM25WeightColumnComment float64
+
+	// 表关联关系
+	RelationshipsFile     string
+
+	// 指标层
+	SemanticLayerFile     string
+
+	// 示例问题
+	ExampleTopK           int
+	ExampleMinScore       float64
+
+	// dbt 导入
+	DbtTargetDir          string
+
+	// 自动生成描述
+	AutoDocSampleRows     bool
+
+	// 多轮对话
+	SessionMaxTurns       int
+	SessionRetention      time.Duration
+	SessionSingleTurnTTL  time.Duration
+
+	// 歧义追问
+	ClarifyEnabled        bool
+	ClarifyScoreMargin    float64
+
+	// 查询历史
+	HistoryRetention      time.Duration
+
+	// 问题路由
+	IntentRouterEnabled   bool
+	IntentLLMEnabled      bool
 }
 
 
@@ -32,6 +110,62 @@ func GetEnvWithDefault(key, defaultValue string) string {
 	return value
 }
 
+// GetEnvIntWithDefault 获取整型环境变量，不存在或无法解析时返回默认值
+func GetEnvIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %s, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// GetEnvFloatWithDefault 获取浮点型环境变量，不存在或无法解析时返回默认值
+func GetEnvFloatWithDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid number for %s: %s, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// GetEnvBoolWithDefault 获取布尔环境变量，不存在或无法解析时返回默认值
+func GetEnvBoolWithDefault(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %s, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// GetEnvDurationWithDefault 获取时长环境变量（如 30m、1h），不存在或无法解析时返回默认值
+func GetEnvDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %s, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
 
 // 初始化配置
 func Init() {
@@ -48,6 +182,55 @@ func Init() {
 		DBPassword:            GetEnvWithDefault("DB_PASSWORD", ""),
 		DBName:                GetEnvWithDefault("DB_NAME", "default"),
 		ServerPort:            GetEnvWithDefault("SERVER_PORT", "8080"),
+		DataDir:               GetEnvWithDefault("DATA_DIR", "./data"),
+
+		JobWorkers:            GetEnvIntWithDefault("JOB_WORKERS", 4),
+		JobQueueSize:          GetEnvIntWithDefault("JOB_QUEUE_SIZE", 100),
+
+		ResultRetention:       GetEnvDurationWithDefault("RESULT_RETENTION", 24*time.Hour),
+
+		SchemaCacheTTL:        GetEnvDurationWithDefault("SCHEMA_CACHE_TTL", 10*time.Minute),
+		TableDetailTTL:        GetEnvDurationWithDefault("TABLE_DETAIL_TTL", time.Hour),
+		TableDetailWorkers:    GetEnvIntWithDefault("TABLE_DETAIL_WORKERS", 4),
+
+		ProfileEnabled:        GetEnvBoolWithDefault("PROFILE_ENABLED", true),
+		ProfileInterval:       GetEnvDurationWithDefault("PROFILE_INTERVAL", 6*time.Hour),
+		ProfileColumns:        GetEnvWithDefault("PROFILE_COLUMNS", ""),
+		ProfileTopN:           GetEnvIntWithDefault("PROFILE_TOP_N", 10),
+		ProfileMaxDistinct:    GetEnvIntWithDefault("PROFILE_MAX_DISTINCT", 50),
+		ProfileSampleRows:     GetEnvIntWithDefault("PROFILE_SAMPLE_ROWS", 100000),
+		PromptSampleTokens:    GetEnvIntWithDefault("PROMPT_SAMPLE_TOKENS", 1000),
+		PromptSchemaTokens:    GetEnvIntWithDefault("PROMPT_SCHEMA_TOKENS", 4000),
+		PromptMaxColumns:      GetEnvIntWithDefault("PROMPT_MAX_COLUMNS", 40),
+
+		EmbeddingProvider:     GetEnvWithDefault("EMBEDDING_PROVIDER", "hash"),
+		EmbeddingAPIURL:       GetEnvWithDefault("EMBEDDING_API_URL", "https://api.openai.com/v1/embeddings"),
+		EmbeddingAPIKey:       os.Getenv("EMBEDDING_API_KEY"),
+		EmbeddingModel:        GetEnvWithDefault("EMBEDDING_MODEL", "text-embedding-3-small"),
+		EmbeddingDim:          GetEnvIntWithDefault("EMBEDDING_DIM", 256),
+		RetrievalTopK:         GetEnvIntWithDefault("RETRIEVAL_TOP_K", 10),
+		RetrievalMinScore:     GetEnvFloatWithDefault("RETRIEVAL_MIN_SCORE", 0.1),
+		RetrievalFusedTopK:    GetEnvIntWithDefault("RETRIEVAL_FUSED_TOP_K", 0),
+		BM25TopK:              GetEnvIntWithDefault("BM25_TOP_K", 10),
+		BM25MinScore:          GetEnvFloatWithDefault("BM25_MIN_SCORE", 0.5),
+		BM25WeightTableName:   GetEnvFloatWithDefault("BM25_WEIGHT_TABLE_NAME", 3),
+		BM25WeightTableComment: GetEnvFloatWithDefault("BM25_WEIGHT_TABLE_COMMENT", 2),
+		BM25WeightColumnName:  GetEnvFloatWithDefault("BM25_WEIGHT_COLUMN_NAME", 1),
+		BM25WeightColumnComment: GetEnvFloatWithDefault("BM25_WEIGHT_COLUMN_COMMENT", 1),
+		RelationshipsFile:     GetEnvWithDefault("RELATIONSHIPS_FILE", "relationships.yaml"),
+		SemanticLayerFile:     GetEnvWithDefault("SEMANTIC_LAYER_FILE", "semantic_layer.yaml"),
+		ExampleTopK:           GetEnvIntWithDefault("EXAMPLE_TOP_K", 3),
+		ExampleMinScore:       GetEnvFloatWithDefault("EXAMPLE_MIN_SCORE", 0.2),
+		DbtTargetDir:          GetEnvWithDefault("DBT_TARGET_DIR", ""),
+		AutoDocSampleRows:     GetEnvBoolWithDefault("AUTODOC_SAMPLE_ROWS", false),
+		SessionMaxTurns:       GetEnvIntWithDefault("SESSION_MAX_TURNS", 10),
+		SessionRetention:      GetEnvDurationWithDefault("SESSION_RETENTION", 7*24*time.Hour),
+		SessionSingleTurnTTL:  GetEnvDurationWithDefault("SESSION_SINGLE_TURN_RETENTION", 24*time.Hour),
+		ClarifyEnabled:        GetEnvBoolWithDefault("CLARIFY_ENABLED", true),
+		ClarifyScoreMargin:    GetEnvFloatWithDefault("CLARIFY_SCORE_MARGIN", 0.1),
+		HistoryRetention:      GetEnvDurationWithDefault("HISTORY_RETENTION", 180*24*time.Hour),
+		IntentRouterEnabled:   GetEnvBoolWithDefault("INTENT_ROUTER_ENABLED", true),
+		IntentLLMEnabled:      GetEnvBoolWithDefault("INTENT_LLM_ENABLED", false),
 	}
 
 	if AppConfig.DeepSeekAPIKey == "" {
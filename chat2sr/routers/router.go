@@ -17,6 +17,74 @@ func SetupRouter() *gin.Engine {
         api.POST("/query", handlers.HandleNLQuery)   
         api.POST("/execute", handlers.HandleExecute)
         api.POST("/analyze", handlers.HandleAnalysis)
+        api.POST("/explain-sql", handlers.HandleExplainSQL)
+        api.POST("/edit-sql", handlers.HandleEditSQL)
+
+        api.POST("/jobs", handlers.HandleSubmitJob)
+        api.GET("/jobs/:id", handlers.HandleGetJob)
+        api.GET("/jobs/:id/result", handlers.HandleGetJobResult)
+        api.DELETE("/jobs/:id", handlers.HandleCancelJob)
+
+        api.GET("/results/:id", handlers.HandleGetResult)
+        api.GET("/results/:id/download", handlers.HandleDownloadResult)
+        api.POST("/results/:id/example", handlers.HandleSaveResultAsExample)
+
+        api.POST("/schema/refresh", handlers.HandleSchemaRefresh)
+        api.GET("/schema/search", handlers.HandleSearchSchema)
+
+        api.GET("/datasources", handlers.HandleListDatasources)
+        api.GET("/tables", handlers.HandleListTables)
+        api.GET("/tables/:name/columns", handlers.HandleListColumns)
+        api.GET("/tables/:name/preview", handlers.HandleTablePreview)
+        api.GET("/tables/:name/profile", handlers.HandleTableProfile)
+
+        api.GET("/glossary", handlers.HandleListGlossary)
+        api.POST("/glossary", handlers.HandleCreateGlossaryEntry)
+        api.GET("/glossary/:id", handlers.HandleGetGlossaryEntry)
+        api.PUT("/glossary/:id", handlers.HandleUpdateGlossaryEntry)
+        api.DELETE("/glossary/:id", handlers.HandleDeleteGlossaryEntry)
+
+        api.GET("/metrics", handlers.HandleListMetrics)
+        api.POST("/metrics/query", handlers.HandleCompileMetricQuery)
+
+        api.GET("/examples", handlers.HandleListExamples)
+        api.POST("/examples", handlers.HandleCreateExample)
+        api.GET("/examples/:id", handlers.HandleGetExample)
+        api.PUT("/examples/:id", handlers.HandleUpdateExample)
+        api.DELETE("/examples/:id", handlers.HandleDeleteExample)
+
+        api.GET("/metadata", handlers.HandleListMetadata)
+        api.POST("/metadata/import", handlers.HandleImportMetadata)
+        api.GET("/metadata/tables/:name", handlers.HandleGetTableMetadata)
+        api.PUT("/metadata/tables/:name", handlers.HandleSetTableMetadata)
+        api.DELETE("/metadata/tables/:name", handlers.HandleDeleteTableMetadata)
+        api.PUT("/metadata/tables/:name/columns/:column", handlers.HandleSetColumnMetadata)
+        api.DELETE("/metadata/tables/:name/columns/:column", handlers.HandleDeleteColumnMetadata)
+        api.POST("/metadata/dbt", handlers.HandleImportDbt)
+
+        api.POST("/sessions", handlers.HandleCreateSession)
+        api.GET("/sessions/:id", handlers.HandleGetSession)
+        api.DELETE("/sessions/:id", handlers.HandleDeleteSession)
+
+        api.GET("/history", handlers.HandleListHistory)
+        api.GET("/history/:id", handlers.HandleGetHistory)
+        api.POST("/history/:id/rerun", handlers.HandleRerunHistory)
+        api.POST("/history/:id/example", handlers.HandleSaveHistoryAsExample)
+
+        api.GET("/saved-queries", handlers.HandleListSavedQueries)
+        api.POST("/saved-queries", handlers.HandleCreateSavedQuery)
+        api.GET("/saved-queries/:id", handlers.HandleGetSavedQuery)
+        api.PUT("/saved-queries/:id", handlers.HandleUpdateSavedQuery)
+        api.DELETE("/saved-queries/:id", handlers.HandleDeleteSavedQuery)
+        api.GET("/saved-queries/:id/versions", handlers.HandleListSavedQueryVersions)
+        api.GET("/saved-queries/:id/diff", handlers.HandleDiffSavedQuery)
+        api.GET("/saved-queries/:id/parameters/:name/options", handlers.HandleSavedQueryParamOptions)
+        api.POST("/saved-queries/:id/run", handlers.HandleRunSavedQuery)
+
+        api.POST("/autodoc", handlers.HandleRunAutoDoc)
+        api.GET("/autodoc/proposals", handlers.HandleListProposals)
+        api.POST("/autodoc/proposals/:id/approve", handlers.HandleApproveProposal)
+        api.POST("/autodoc/proposals/:id/reject", handlers.HandleRejectProposal)
     }
 
     return router
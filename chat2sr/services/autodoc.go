@@ -0,0 +1,409 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// 待审核描述的状态
+const (
+    ProposalStatusPending  = "pending"
+    ProposalStatusApproved = "approved"
+    ProposalStatusRejected = "rejected"
+)
+
+const (
+    // 开启 AUTODOC_SAMPLE_ROWS 时每张表采样的行数
+    autoDocSampleRows = 5
+    // 每次请求模型描述的字段数上限，避免提示词过长
+    autoDocMaxColumns = 40
+)
+
+var (
+    ErrProposalNotFound = errors.New("proposal not found")
+    ErrProposalReviewed = errors.New("proposal has already been reviewed")
+    ErrAutoDocRunning   = errors.New("auto documentation is already running")
+)
+
+// DocProposal 模型为缺少注释的表或字段生成的描述，审核通过后写入补充元数据
+type DocProposal struct {
+    ID          string    `json:"id"`
+    Table       string    `json:"table"`
+    Column      string    `json:"column,omitempty"`
+    Description string    `json:"description"`
+    Status      string    `json:"status"`
+    CreatedAt   time.Time `json:"created_at"`
+    ReviewedAt  time.Time `json:"reviewed_at,omitempty"`
+    Reviewer    string    `json:"reviewer,omitempty"`
+}
+
+// AutoDocSummary 一次自动生成描述的统计
+type AutoDocSummary struct {
+    Tables    int      `json:"tables"`
+    Proposals int      `json:"proposals"`
+    Failed    []string `json:"failed,omitempty"`
+}
+
+type proposalStore struct {
+    mu        sync.Mutex
+    loaded    bool
+    proposals map[string]*DocProposal
+}
+
+var (
+    proposals = &proposalStore{}
+
+    autoDocMu      sync.Mutex
+    autoDocRunning bool
+)
+
+func proposalsPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "doc_proposals.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载待审核描述，调用方需持有锁
+func (s *proposalStore) ensureLoaded() {
+    if s.loaded {
+        return
+    }
+    s.loaded = true
+    s.proposals = make(map[string]*DocProposal)
+    if err := loadJSONFile(proposalsPath(), &s.proposals); err != nil {
+        log.Printf("Error loading doc proposals: %s", err.Error())
+    }
+}
+
+// hasProposalLocked 表或字段是否已有待审核或被驳回的描述，避免重复生成，调用方需持有锁
+func (s *proposalStore) hasProposalLocked(table, column string) bool {
+    for _, p := range s.proposals {
+        if p.Status != ProposalStatusApproved && p.Table == table && p.Column == column {
+            return true
+        }
+    }
+    return false
+}
+
+// RunAutoDoc 为缺少注释的表和字段生成描述，tables 为空时处理所有表，maxTables 大于0时限制处理的表数
+func RunAutoDoc(tables []string, maxTables int) (*AutoDocSummary, error) {
+    if !acquireAutoDoc() {
+        return nil, ErrAutoDocRunning
+    }
+    defer releaseAutoDoc()
+    return runAutoDoc(tables, maxTables)
+}
+
+// StartAutoDoc 在后台生成描述，已有任务在运行时返回 ErrAutoDocRunning
+func StartAutoDoc(tables []string, maxTables int) error {
+    if !acquireAutoDoc() {
+        return ErrAutoDocRunning
+    }
+    go func() {
+        defer releaseAutoDoc()
+        if _, err := runAutoDoc(tables, maxTables); err != nil {
+            log.Printf("Error running auto documentation: %s", err.Error())
+        }
+    }()
+    return nil
+}
+
+func acquireAutoDoc() bool {
+    autoDocMu.Lock()
+    defer autoDocMu.Unlock()
+    if autoDocRunning {
+        return false
+    }
+    autoDocRunning = true
+    return true
+}
+
+func releaseAutoDoc() {
+    autoDocMu.Lock()
+    autoDocRunning = false
+    autoDocMu.Unlock()
+}
+
+func runAutoDoc(tables []string, maxTables int) (*AutoDocSummary, error) {
+    schemas, err := loadAutoDocTables()
+    if err != nil {
+        return nil, err
+    }
+    if len(tables) == 0 {
+        for name := range schemas {
+            tables = append(tables, name)
+        }
+        sort.Strings(tables)
+    }
+
+    summary := &AutoDocSummary{}
+    for _, name := range tables {
+        if maxTables > 0 && summary.Tables >= maxTables {
+            break
+        }
+        table, ok := schemas[name]
+        if !ok {
+            log.Printf("Error documenting table %s: %s", name, ErrTableNotFound.Error())
+            summary.Failed = append(summary.Failed, name)
+            continue
+        }
+        if IsTableDeprecated(name) {
+            continue
+        }
+        created, err := documentTable(table)
+        if err != nil {
+            log.Printf("Error documenting table %s: %s", name, err.Error())
+            summary.Failed = append(summary.Failed, name)
+            continue
+        }
+        if created > 0 {
+            summary.Tables++
+            summary.Proposals += created
+        }
+    }
+    log.Printf("Auto documentation finished: %d proposals for %d tables", summary.Proposals, summary.Tables)
+    return summary, nil
+}
+
+// loadAutoDocTables 一次取出所有表结构，表结构缓存未加载时（如命令行执行）直接查询一次数据库
+func loadAutoDocTables() (map[string]*TableSchema, error) {
+    schemas := make(map[string]*TableSchema)
+    if catalog, err := GetCatalog(DefaultDatasource); err == nil && catalog.Loaded() {
+        for _, info := range catalog.Tables() {
+            if table, ok := catalog.Table(info.Name); ok {
+                schemas[info.Name] = table
+            }
+        }
+        return schemas, nil
+    }
+
+    tables, err := GetAllTablesWithComments()
+    if err != nil {
+        return nil, err
+    }
+    columns, err := GetAllColumnsWithComments()
+    if err != nil {
+        return nil, err
+    }
+    for _, table := range tables {
+        schemas[table.Name] = &TableSchema{Name: table.Name, Comment: table.Comment, Columns: columns[table.Name]}
+    }
+    return schemas, nil
+}
+
+// documentTable 统计字段取值并请模型为缺少注释的表和字段生成描述，返回新增的待审核描述数。
+// 只有开启 AUTODOC_SAMPLE_ROWS 时才附带原始数据行
+func documentTable(table *TableSchema) (int, error) {
+    proposals.mu.Lock()
+    proposals.ensureLoaded()
+    needTable := table.Comment == "" && !proposals.hasProposalLocked(table.Name, "")
+    var columns []string
+    for _, col := range table.Columns {
+        if col.Comment == "" && !proposals.hasProposalLocked(table.Name, col.Name) && len(columns) < autoDocMaxColumns {
+            columns = append(columns, col.Name)
+        }
+    }
+    proposals.mu.Unlock()
+    if !needTable && len(columns) == 0 {
+        return 0, nil
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+    defer cancel()
+    var rows []map[string]interface{}
+    if config.AppConfig.AutoDocSampleRows {
+        var err error
+        if _, rows, err = previewTable(ctx, table, autoDocSampleRows); err != nil {
+            return 0, err
+        }
+    }
+    var stats []ColumnStats
+    if len(columns) > 0 {
+        profile, err := profileTable(ctx, table, columns)
+        if err != nil {
+            return 0, err
+        }
+        stats = profile.Columns
+    }
+
+    response, err := ProcessQuery(buildAutoDocPrompt(table, columns, rows, stats))
+    if err != nil {
+        return 0, err
+    }
+    var proposed struct {
+        Table   string            `json:"table"`
+        Columns map[string]string `json:"columns"`
+    }
+    if err := json.Unmarshal([]byte(extractJSONObject(response)), &proposed); err != nil {
+        return 0, fmt.Errorf("failed to parse proposed descriptions: %v", err)
+    }
+
+    proposals.mu.Lock()
+    defer proposals.mu.Unlock()
+    now := time.Now()
+    created := 0
+    add := func(column, description string) {
+        description = strings.TrimSpace(description)
+        if description == "" {
+            return
+        }
+        p := &DocProposal{
+            ID:          newID(),
+            Table:       table.Name,
+            Column:      column,
+            Description: description,
+            Status:      ProposalStatusPending,
+            CreatedAt:   now,
+        }
+        proposals.proposals[p.ID] = p
+        created++
+    }
+    if needTable {
+        add("", proposed.Table)
+    }
+    for _, column := range columns {
+        add(column, proposed.Columns[column])
+    }
+    if created > 0 {
+        if err := saveJSONFile(proposalsPath(), proposals.proposals); err != nil {
+            return 0, err
+        }
+    }
+    return created, nil
+}
+
+func buildAutoDocPrompt(table *TableSchema, columns []string, rows []map[string]interface{}, stats []ColumnStats) string {
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("表名: %s\n", table.Name))
+    if table.Comment != "" {
+        b.WriteString(fmt.Sprintf("表说明: %s\n", table.Comment))
+    }
+    b.WriteString("字段:\n")
+    for _, col := range table.Columns {
+        line := fmt.Sprintf("- %s %s", col.Name, col.Type)
+        if col.Comment != "" {
+            line += " 说明: " + col.Comment
+        }
+        b.WriteString(line + "\n")
+    }
+
+    if len(stats) > 0 {
+        b.WriteString("\n字段取值分布:\n")
+        for _, s := range stats {
+            line := fmt.Sprintf("- %s: 空值比例 %.2f, 近似基数 %d", s.Column, s.NullRatio, s.DistinctCount)
+            if s.Min != "" || s.Max != "" {
+                line += fmt.Sprintf(", 范围 %s ~ %s", s.Min, s.Max)
+            }
+            if len(s.TopValues) > 0 {
+                var values []string
+                for _, v := range s.TopValues {
+                    values = append(values, fmt.Sprintf("%s(%d)", v.Value, v.Count))
+                }
+                line += ", 高频取值 " + strings.Join(values, ", ")
+            }
+            b.WriteString(line + "\n")
+        }
+    }
+
+    if len(rows) > 0 {
+        sample, _ := json.Marshal(rows)
+        b.WriteString("\n样例数据:\n" + string(sample) + "\n")
+    }
+
+    columnsJSON, _ := json.Marshal(columns)
+    return fmt.Sprintf(`你是数据仓库的数据治理专家。根据下面的表结构、字段取值分布和样例数据，为缺少说明的表和字段编写简洁的中文描述，
+说明业务含义，必要时注明单位、枚举值含义或时间口径。不确定的内容不要编造，返回空字符串。
+
+%s
+需要描述的字段: %s
+只返回一个JSON对象，不要包含任何解释，格式如下：
+{"table": "表的描述（表已有说明时返回空字符串）", "columns": {"字段名": "字段描述"}}`, b.String(), string(columnsJSON))
+}
+
+// ListProposals 列出待审核描述，status 为空时返回全部
+func ListProposals(status, table string) []DocProposal {
+    proposals.mu.Lock()
+    proposals.ensureLoaded()
+    var list []DocProposal
+    for _, p := range proposals.proposals {
+        if (status == "" || p.Status == status) && (table == "" || p.Table == table) {
+            list = append(list, *p)
+        }
+    }
+    proposals.mu.Unlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].Table != list[j].Table {
+            return list[i].Table < list[j].Table
+        }
+        return list[i].Column < list[j].Column
+    })
+    return list
+}
+
+// ApproveProposal 审核通过，description 不为空时使用修改后的描述，写入补充元数据
+func ApproveProposal(id, description, reviewer string) (*DocProposal, error) {
+    proposals.mu.Lock()
+    defer proposals.mu.Unlock()
+    proposals.ensureLoaded()
+
+    p, ok := proposals.proposals[id]
+    if !ok {
+        return nil, ErrProposalNotFound
+    }
+    if p.Status != ProposalStatusPending {
+        return nil, ErrProposalReviewed
+    }
+    if description = strings.TrimSpace(description); description != "" {
+        p.Description = description
+    }
+
+    meta := &TableMetadata{}
+    if p.Column == "" {
+        meta.Description = p.Description
+    } else {
+        meta.Columns = map[string]*MetadataEntry{p.Column: {Description: p.Description}}
+    }
+    if _, err := ImportMetadata(map[string]*TableMetadata{p.Table: meta}); err != nil {
+        return nil, err
+    }
+
+    p.Status = ProposalStatusApproved
+    p.ReviewedAt = time.Now()
+    p.Reviewer = reviewer
+    if err := saveJSONFile(proposalsPath(), proposals.proposals); err != nil {
+        return nil, err
+    }
+    copied := *p
+    return &copied, nil
+}
+
+// RejectProposal 驳回描述
+func RejectProposal(id, reviewer string) (*DocProposal, error) {
+    proposals.mu.Lock()
+    defer proposals.mu.Unlock()
+    proposals.ensureLoaded()
+
+    p, ok := proposals.proposals[id]
+    if !ok {
+        return nil, ErrProposalNotFound
+    }
+    if p.Status != ProposalStatusPending {
+        return nil, ErrProposalReviewed
+    }
+    p.Status = ProposalStatusRejected
+    p.ReviewedAt = time.Now()
+    p.Reviewer = reviewer
+    if err := saveJSONFile(proposalsPath(), proposals.proposals); err != nil {
+        return nil, err
+    }
+    copied := *p
+    return &copied, nil
+}
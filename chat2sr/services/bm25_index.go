@@ -0,0 +1,202 @@
+package services
+
+import (
+    "log"
+    "math"
+    "sort"
+    "sync"
+    "chat2sr/config"
+)
+
+// BM25 参数：k1 控制词频饱和速度，b 控制字段长度归一化的强度
+const (
+    bm25K1 = 1.2
+    bm25B  = 0.75
+)
+
+// 表文档的字段：表名、表注释、字段名、字段注释
+const (
+    bm25FieldTableName = iota
+    bm25FieldTableComment
+    bm25FieldColumnName
+    bm25FieldColumnComment
+    bm25FieldCount
+)
+
+type bm25Document struct {
+    table  string
+    terms  [bm25FieldCount]map[string]int
+    length [bm25FieldCount]int
+}
+
+// BM25Index 基于表结构缓存构建的 BM25F 索引，只保存在内存中
+type BM25Index struct {
+    mu        sync.RWMutex
+    docs      []*bm25Document
+    docFreq   map[string]int
+    avgLength [bm25FieldCount]float64
+}
+
+var (
+    bm25IndexesMu sync.Mutex
+    bm25Indexes   = make(map[string]*BM25Index)
+)
+
+// GetBM25Index 获取数据源的 BM25 索引
+func GetBM25Index(datasource string) *BM25Index {
+    bm25IndexesMu.Lock()
+    defer bm25IndexesMu.Unlock()
+
+    if index, ok := bm25Indexes[datasource]; ok {
+        return index
+    }
+    index := &BM25Index{docFreq: make(map[string]int)}
+    bm25Indexes[datasource] = index
+    return index
+}
+
+// UpdateBM25Index 根据最新的表结构重建 BM25 索引
+func UpdateBM25Index(catalog *SchemaCatalog) {
+    seedDictWords(catalog)
+
+    var docs []*bm25Document
+    docFreq := make(map[string]int)
+    var totalLength [bm25FieldCount]int
+
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+
+        doc := &bm25Document{table: table.Name}
+        fields := [bm25FieldCount][]string{
+            bm25FieldTableName:    Tokenize(table.Name),
+            bm25FieldTableComment: Tokenize(table.Comment),
+        }
+        for _, col := range table.Columns {
+            fields[bm25FieldColumnName] = append(fields[bm25FieldColumnName], Tokenize(col.Name)...)
+            fields[bm25FieldColumnComment] = append(fields[bm25FieldColumnComment], Tokenize(col.Comment)...)
+        }
+
+        seen := make(map[string]bool)
+        for f, tokens := range fields {
+            doc.terms[f] = make(map[string]int)
+            for _, token := range tokens {
+                doc.terms[f][token]++
+                if !seen[token] {
+                    seen[token] = true
+                    docFreq[token]++
+                }
+            }
+            doc.length[f] = len(tokens)
+            totalLength[f] += len(tokens)
+        }
+        docs = append(docs, doc)
+    }
+
+    var avgLength [bm25FieldCount]float64
+    if len(docs) > 0 {
+        for f := range avgLength {
+            avgLength[f] = float64(totalLength[f]) / float64(len(docs))
+        }
+    }
+
+    index := GetBM25Index(catalog.Datasource)
+    index.mu.Lock()
+    index.docs = docs
+    index.docFreq = docFreq
+    index.avgLength = avgLength
+    index.mu.Unlock()
+
+    log.Printf("Updated BM25 index for %s: %d tables, %d terms", catalog.Datasource, len(docs), len(docFreq))
+}
+
+// seedDictWords 构建索引前将表和字段注释、业务术语加入分词词典
+func seedDictWords(catalog *SchemaCatalog) {
+    var texts []string
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+        texts = append(texts, table.Comment)
+        for _, col := range table.Columns {
+            texts = append(texts, col.Comment)
+        }
+    }
+    for _, entry := range glossary.snapshot() {
+        texts = append(texts, entry.names()...)
+    }
+    if added := AddDictWords(texts); added > 0 {
+        log.Printf("Added %d domain words to the tokenizer dictionary", added)
+    }
+}
+
+// Search 按 BM25F 得分返回与问题最相关的表
+func (idx *BM25Index) Search(question string, topK int, minScore float64) []TableScore {
+    terms := ExpandAliases(Tokenize(question))
+    weights := bm25FieldWeights()
+
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    n := float64(len(idx.docs))
+    var scores []TableScore
+    for _, doc := range idx.docs {
+        score := 0.0
+        for _, term := range terms {
+            df := idx.docFreq[term]
+            if df == 0 {
+                continue
+            }
+
+            // 各字段的词频按字段长度归一化后加权求和，再统一做饱和处理
+            tf := 0.0
+            for f := 0; f < bm25FieldCount; f++ {
+                count := doc.terms[f][term]
+                if count == 0 || idx.avgLength[f] == 0 {
+                    continue
+                }
+                norm := 1 - bm25B + bm25B*float64(doc.length[f])/idx.avgLength[f]
+                tf += weights[f] * float64(count) / norm
+            }
+            if tf == 0 {
+                continue
+            }
+
+            idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+            score += idf * tf / (bm25K1 + tf)
+        }
+        if score > 0 && score >= minScore {
+            scores = append(scores, TableScore{Table: doc.table, Score: score})
+        }
+    }
+
+    sort.Slice(scores, func(i, j int) bool {
+        if scores[i].Score == scores[j].Score {
+            return scores[i].Table < scores[j].Table
+        }
+        return scores[i].Score > scores[j].Score
+    })
+    if len(scores) > topK {
+        scores = scores[:topK]
+    }
+    return scores
+}
+
+// Size 索引中的表数量
+func (idx *BM25Index) Size() int {
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+    return len(idx.docs)
+}
+
+func bm25FieldWeights() [bm25FieldCount]float64 {
+    return [bm25FieldCount]float64{
+        bm25FieldTableName:     config.AppConfig.BM25WeightTableName,
+        bm25FieldTableComment:  config.AppConfig.BM25WeightTableComment,
+        bm25FieldColumnName:    config.AppConfig.BM25WeightColumnName,
+        bm25FieldColumnComment: config.AppConfig.BM25WeightColumnComment,
+    }
+}
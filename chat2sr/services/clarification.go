@@ -0,0 +1,351 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// 追问的类型
+const (
+    ClarifyTable     = "table"
+    ClarifyTimeRange = "time_range"
+    ClarifyMetric    = "metric"
+)
+
+const (
+    // 待回答的追问保留时长
+    clarificationRetention = 24 * time.Hour
+    // 候选表追问最多列出的选项数
+    clarifyMaxTableOptions = 4
+    // 未知指标追问最多列出的已定义指标数
+    clarifyMaxMetricOptions = 4
+    // 未知指标选择"按字面理解"时的取值
+    ClarifyLiteral = "literal"
+)
+
+var (
+    ErrClarificationNotFound = errors.New("clarification not found or expired")
+    ErrClarificationInvalid  = errors.New("clarification answers are incomplete or invalid")
+
+    // 含糊的时间表述
+    vagueTimePattern = regexp.MustCompile(`最近|近期|近来|这段时间|这阵子|(?i)\b(recent|recently|latest|lately)\b`)
+    // 明确的时间范围：近7天、上个月、2024-05、本季度等
+    explicitTimePattern = regexp.MustCompile(`[0-9一二两三四五六七八九十半]+\s*(个)?\s*(天|日|周|星期|月|季度|年|小时)|今天|昨天|前天|今日|昨日|本周|上周|这周|本月|上月|上个月|这个月|本季度|上季度|今年|去年|\d{4}[-/年]\d{1,2}|(?i)\b(today|yesterday|last|this|past)\s+\w+`)
+    // 英文缩写指标，如 GMV、ARPU
+    acronymMetricPattern = regexp.MustCompile(`\b[A-Z]{2,6}\b`)
+)
+
+// ClarifyOption 追问的选项
+type ClarifyOption struct {
+    Value string `json:"value"`
+    Label string `json:"label"`
+}
+
+// ClarifyQuestion 需要用户确认的问题
+type ClarifyQuestion struct {
+    ID      string          `json:"id"`
+    Kind    string          `json:"kind"`
+    Prompt  string          `json:"prompt"`
+    Options []ClarifyOption `json:"options"`
+}
+
+// Clarification 等待用户回答的追问，保存已完成的选表结果，回答后直接继续生成SQL
+type Clarification struct {
+    ID        string            `json:"id"`
+    SessionID string            `json:"session_id,omitempty"`
+    Question  string            `json:"question"`
+    Tables    []string          `json:"tables"`
+    Retrieval *TableRetrieval   `json:"retrieval,omitempty"`
+    Questions []ClarifyQuestion `json:"questions"`
+    CreatedAt time.Time         `json:"created_at"`
+}
+
+// ResolvedClarification 结合用户回答后的问题和表
+type ResolvedClarification struct {
+    SessionID string
+    Question  string
+    Tables    []string
+    Retrieval *TableRetrieval
+}
+
+type clarificationStore struct {
+    mu      sync.Mutex
+    loaded  bool
+    pending map[string]*Clarification
+}
+
+var clarifications = &clarificationStore{}
+
+func clarificationsPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "clarifications.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载待回答的追问，调用方需持有锁
+func (s *clarificationStore) ensureLoaded() {
+    if s.loaded {
+        return
+    }
+    s.loaded = true
+    s.pending = make(map[string]*Clarification)
+    if err := loadJSONFile(clarificationsPath(), &s.pending); err != nil {
+        log.Printf("Error loading clarifications: %s", err.Error())
+    }
+}
+
+// saveLocked 清理过期的追问并持久化，调用方需持有锁
+func (s *clarificationStore) saveLocked() error {
+    now := time.Now()
+    for id, pending := range s.pending {
+        if now.Sub(pending.CreatedAt) > clarificationRetention {
+            delete(s.pending, id)
+        }
+    }
+    return saveJSONFile(clarificationsPath(), s.pending)
+}
+
+// DetectAmbiguity 检查问题是否存在歧义：候选表得分接近而模型只选了其中一张、时间范围含糊、指标没有定义
+func DetectAmbiguity(question string, retrieval *TableRetrieval, candidates []TableInfo, selected []string) []ClarifyQuestion {
+    if !config.AppConfig.ClarifyEnabled {
+        return nil
+    }
+    var questions []ClarifyQuestion
+    if q := clarifyTables(retrieval, candidates, selected); q != nil {
+        questions = append(questions, *q)
+    }
+    if vagueTimePattern.MatchString(question) && !explicitTimePattern.MatchString(question) {
+        questions = append(questions, ClarifyQuestion{
+            ID:     ClarifyTimeRange,
+            Kind:   ClarifyTimeRange,
+            Prompt: "问题中的时间范围不明确，请选择要统计的时间范围",
+            Options: []ClarifyOption{
+                {Value: "最近7天", Label: "最近7天"},
+                {Value: "最近30天", Label: "最近30天"},
+                {Value: "本月至今", Label: "本月至今"},
+                {Value: "最近90天", Label: "最近90天"},
+            },
+        })
+    }
+    for _, term := range unknownMetricTerms(question, candidates) {
+        questions = append(questions, ClarifyQuestion{
+            ID:      ClarifyMetric + ":" + term,
+            Kind:    ClarifyMetric,
+            Prompt:  fmt.Sprintf("没有找到\"%s\"的定义，请选择对应的指标或说明计算口径", term),
+            Options: metricOptions(term),
+        })
+    }
+    return questions
+}
+
+// clarifyTables 检索得分最高的几张表得分接近，而模型只选中其中一部分时，请用户确认使用哪张表
+func clarifyTables(retrieval *TableRetrieval, candidates []TableInfo, selected []string) *ClarifyQuestion {
+    if retrieval == nil {
+        return nil
+    }
+    scores := retrieval.BM25
+    if len(scores) < 2 {
+        scores = retrieval.Embedding
+    }
+    if len(scores) < 2 || scores[0].Score <= 0 {
+        return nil
+    }
+
+    threshold := scores[0].Score * (1 - config.AppConfig.ClarifyScoreMargin)
+    var similar []string
+    for _, score := range scores {
+        if score.Score < threshold || len(similar) >= clarifyMaxTableOptions {
+            break
+        }
+        similar = append(similar, score.Table)
+    }
+    if len(similar) < 2 {
+        return nil
+    }
+    chosen := 0
+    for _, table := range similar {
+        if containsString(selected, table) {
+            chosen++
+        }
+    }
+    // 模型选中了全部接近的表，说明问题本身需要多表关联
+    if chosen == len(similar) {
+        return nil
+    }
+
+    comments := make(map[string]string, len(candidates))
+    for _, table := range candidates {
+        comments[table.Name] = table.Comment
+    }
+    question := &ClarifyQuestion{
+        ID:     ClarifyTable,
+        Kind:   ClarifyTable,
+        Prompt: "有多张表都可能满足需求，请选择要使用的表",
+    }
+    for _, table := range similar {
+        label := table
+        if comments[table] != "" {
+            label = fmt.Sprintf("%s（%s）", table, comments[table])
+        }
+        question.Options = append(question.Options, ClarifyOption{Value: table, Label: label})
+    }
+    return question
+}
+
+// unknownMetricTerms 找出问题中既不在术语表、指标层中，也不出现在候选表注释和字段中的比率类指标或英文缩写
+func unknownMetricTerms(question string, candidates []TableInfo) []string {
+    var terms []string
+    tokens := Tokenize(question)
+    for i, token := range tokens {
+        switch {
+        case (token == "占比" || token == "比例") && i > 0:
+            terms = appendUnique(terms, tokens[i-1]+token)
+        case strings.HasSuffix(token, "率") && len([]rune(token)) > 1:
+            terms = appendUnique(terms, token)
+        }
+    }
+    for _, acronym := range acronymMetricPattern.FindAllString(question, -1) {
+        terms = appendUnique(terms, acronym)
+    }
+    if len(terms) == 0 {
+        return nil
+    }
+
+    var known strings.Builder
+    for _, entry := range MatchGlossary(question) {
+        known.WriteString(strings.ToLower(strings.Join(entry.names(), " ")) + " ")
+    }
+    if layer, err := CurrentSemanticLayer(); err == nil {
+        for _, metric := range layer.MatchMetrics(question) {
+            names := append([]string{metric.Name, metric.Label}, metric.Synonyms...)
+            known.WriteString(strings.ToLower(strings.Join(names, " ")) + " ")
+        }
+    }
+    for _, table := range candidates {
+        known.WriteString(strings.ToLower(table.Name+" "+table.Comment) + " ")
+        for _, col := range cachedColumns(table.Name) {
+            known.WriteString(strings.ToLower(col.Name+" "+col.Comment) + " ")
+        }
+    }
+
+    var unknown []string
+    for _, term := range terms {
+        if !strings.Contains(known.String(), strings.ToLower(term)) {
+            unknown = append(unknown, term)
+        }
+    }
+    return unknown
+}
+
+// metricOptions 名称与未知指标相近的已定义指标和术语，最后一项为按字面含义理解
+func metricOptions(term string) []ClarifyOption {
+    stem := strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(term, "率"), "占比"), "比例"))
+    var options []ClarifyOption
+    add := func(label, definition string) {
+        if len(options) >= clarifyMaxMetricOptions || stem == "" || !strings.Contains(strings.ToLower(label), stem) {
+            return
+        }
+        for _, option := range options {
+            if option.Label == label {
+                return
+            }
+        }
+        options = append(options, ClarifyOption{Value: label + ": " + definition, Label: label})
+    }
+    if layer, err := CurrentSemanticLayer(); err == nil {
+        for _, metric := range layer.Metrics {
+            add(firstNonEmpty(metric.Label, metric.Name), firstNonEmpty(metric.Description, metric.Expression))
+        }
+    }
+    for _, entry := range ListGlossary("") {
+        add(entry.Term, firstNonEmpty(entry.Description, entry.Expression))
+    }
+    return append(options, ClarifyOption{Value: ClarifyLiteral, Label: "按字面含义理解"})
+}
+
+// CreateClarification 保存待回答的追问
+func CreateClarification(pending Clarification) (*Clarification, error) {
+    clarifications.mu.Lock()
+    defer clarifications.mu.Unlock()
+    clarifications.ensureLoaded()
+
+    pending.ID = newID()
+    pending.CreatedAt = time.Now()
+    clarifications.pending[pending.ID] = &pending
+    if err := clarifications.saveLocked(); err != nil {
+        delete(clarifications.pending, pending.ID)
+        return nil, err
+    }
+    copied := pending
+    return &copied, nil
+}
+
+// ResolveClarification 结合用户的回答得到补充说明后的问题和最终使用的表，追问回答后即失效
+func ResolveClarification(id string, answers map[string]string) (*ResolvedClarification, error) {
+    clarifications.mu.Lock()
+    defer clarifications.mu.Unlock()
+    clarifications.ensureLoaded()
+
+    pending, ok := clarifications.pending[id]
+    if !ok || time.Since(pending.CreatedAt) > clarificationRetention {
+        return nil, ErrClarificationNotFound
+    }
+
+    resolved := &ResolvedClarification{
+        SessionID: pending.SessionID,
+        Tables:    pending.Tables,
+        Retrieval: pending.Retrieval,
+    }
+    var notes []string
+    for _, q := range pending.Questions {
+        answer := strings.TrimSpace(answers[q.ID])
+        if answer == "" {
+            return nil, fmt.Errorf("%w: missing answer for %s", ErrClarificationInvalid, q.ID)
+        }
+        switch q.Kind {
+        case ClarifyTable:
+            // 选项之外的表不允许，其余接近的表从选表结果中去掉
+            if !optionsContain(q.Options, answer) {
+                return nil, fmt.Errorf("%w: unknown table %s", ErrClarificationInvalid, answer)
+            }
+            var tables []string
+            for _, table := range resolved.Tables {
+                if table != answer && !optionsContain(q.Options, table) {
+                    tables = append(tables, table)
+                }
+            }
+            resolved.Tables = append([]string{answer}, tables...)
+        case ClarifyTimeRange:
+            notes = append(notes, "时间范围: "+answer)
+        case ClarifyMetric:
+            if answer != ClarifyLiteral {
+                notes = append(notes, strings.TrimPrefix(q.ID, ClarifyMetric+":")+" 指 "+answer)
+            }
+        }
+    }
+
+    resolved.Question = pending.Question
+    if len(notes) > 0 {
+        resolved.Question = fmt.Sprintf("%s（补充说明：%s）", pending.Question, strings.Join(notes, "；"))
+    }
+
+    delete(clarifications.pending, id)
+    if err := clarifications.saveLocked(); err != nil {
+        log.Printf("Error saving clarifications: %s", err.Error())
+    }
+    return resolved, nil
+}
+
+func optionsContain(options []ClarifyOption, value string) bool {
+    for _, option := range options {
+        if option.Value == value {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "unicode/utf8"
+    "chat2sr/config"
+)
+
+// PromptColumns 写入提示词的字段及被省略的字段数
+type PromptColumns struct {
+    Columns []ColumnInfo
+    Omitted int
+}
+
+type rankedColumn struct {
+    column   ColumnInfo
+    position int
+    required bool
+    score    float64
+}
+
+// SelectPromptColumns 按与问题的相关度挑选写入提示词的字段，主键、分区和关联字段始终保留，
+// 其余字段按得分依次加入，直到超出 token 预算或字段数上限。结果保持表中的原始顺序
+func SelectPromptColumns(schema *TableSchema, question string, tokenBudget int) PromptColumns {
+    terms := tokenSet(ExpandAliases(Tokenize(question)))
+    questionLower := strings.ToLower(question)
+    keys := tableKeyColumns(schema)
+    graph := CurrentJoinGraph()
+
+    var ranked []rankedColumn
+    deprecated := 0
+    for i, col := range schema.Columns {
+        // 已废弃的字段不写入提示词
+        if IsColumnDeprecated(schema.Name, col.Name) {
+            deprecated++
+            continue
+        }
+        ranked = append(ranked, rankedColumn{
+            column:   col,
+            position: i,
+            required: keys[col.Name] || isJoinKeyColumn(col.Name) || graph.IsJoinColumn(schema.Name, col.Name),
+            score:    columnRelevance(col, terms, questionLower),
+        })
+    }
+    sort.SliceStable(ranked, func(i, j int) bool {
+        if ranked[i].required != ranked[j].required {
+            return ranked[i].required
+        }
+        return ranked[i].score > ranked[j].score
+    })
+
+    maxColumns := config.AppConfig.PromptMaxColumns
+    var selected []rankedColumn
+    optional := 0
+    for _, rc := range ranked {
+        cost := EstimateTokens(formatColumnLine(rc.column))
+        if !rc.required {
+            if cost > tokenBudget || (maxColumns > 0 && optional >= maxColumns) {
+                continue
+            }
+            optional++
+        }
+        tokenBudget -= cost
+        selected = append(selected, rc)
+    }
+
+    sort.Slice(selected, func(i, j int) bool {
+        return selected[i].position < selected[j].position
+    })
+    result := PromptColumns{Omitted: len(schema.Columns) - deprecated - len(selected)}
+    for _, rc := range selected {
+        result.Columns = append(result.Columns, rc.column)
+    }
+    return result
+}
+
+// SchemaTokenBudget 每张表可用的字段列表 token 预算
+func SchemaTokenBudget(tableCount int) int {
+    if tableCount <= 0 {
+        return config.AppConfig.PromptSchemaTokens
+    }
+    return config.AppConfig.PromptSchemaTokens / tableCount
+}
+
+// FormatOmittedColumns 提示被省略的字段，避免模型误以为表中只有这些字段
+func FormatOmittedColumns(omitted int) string {
+    if omitted <= 0 {
+        return ""
+    }
+    return fmt.Sprintf("（另有 %d 个字段与本次问题关系不大，未列出）\n", omitted)
+}
+
+// columnRelevance 字段名或注释与问题的匹配程度。字段名按整词匹配，避免 id、dt 这类短字段名命中 video、width 等单词；
+// 只有一个字的注释不做整体匹配
+func columnRelevance(col ColumnInfo, terms map[string]bool, questionLower string) float64 {
+    score := 0.0
+    if containsTerm(questionLower, strings.ToLower(col.Name)) {
+        score += 3
+    }
+    if comment := strings.ToLower(strings.TrimSpace(col.Comment)); utf8.RuneCountInString(comment) > 1 && containsTerm(questionLower, comment) {
+        score += 3
+    }
+    for _, token := range Tokenize(col.Name) {
+        if terms[token] {
+            score += 1
+        }
+    }
+    for _, token := range Tokenize(col.Comment) {
+        if terms[token] {
+            score += 1
+        }
+    }
+    return score
+}
+
+// tableKeyColumns 表的排序键、分区字段和分桶字段
+func tableKeyColumns(schema *TableSchema) map[string]bool {
+    keys := make(map[string]bool)
+    if schema.Details == nil {
+        return keys
+    }
+    for _, list := range [][]string{schema.Details.KeyColumns, schema.Details.PartitionColumns, schema.Details.DistributionKeys} {
+        for _, name := range list {
+            keys[name] = true
+        }
+    }
+    return keys
+}
+
+// isJoinKeyColumn 按命名约定判断是否为关联字段，如 id、user_id
+func isJoinKeyColumn(name string) bool {
+    name = strings.ToLower(name)
+    return name == "id" || strings.HasSuffix(name, "_id")
+}
+
+// formatColumnLine 估算 token 时使用的字段描述
+func formatColumnLine(col ColumnInfo) string {
+    return fmt.Sprintf("- %s %s %s", col.Name, col.Type, col.Comment)
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestColumnRelevance(t *testing.T) {
+    tests := []struct {
+        name     string
+        col      ColumnInfo
+        question string
+        matched  bool
+    }{
+        {"whole word", ColumnInfo{Name: "dt"}, "按 dt 统计订单数", true},
+        {"short name inside word", ColumnInfo{Name: "dt"}, "video width by region", false},
+        {"id inside word", ColumnInfo{Name: "id"}, "各省份的 video 播放量", false},
+        {"comment", ColumnInfo{Name: "pay_amount", Comment: "支付金额"}, "上月支付金额最高的用户", true},
+        {"single character comment", ColumnInfo{Name: "c1", Comment: "量"}, "播放量", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            terms := tokenSet(ExpandAliases(Tokenize(tt.question)))
+            score := columnRelevance(tt.col, terms, strings.ToLower(tt.question))
+            if (score >= 3) != tt.matched {
+                t.Errorf("columnRelevance(%q, %q) = %v, matched want %v", tt.col.Name, tt.question, score, tt.matched)
+            }
+        })
+    }
+}
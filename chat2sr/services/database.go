@@ -1,6 +1,7 @@
 package services
 
 import (
+    "context"
     "database/sql"
     "fmt"
     "chat2sr/config"
@@ -13,8 +14,17 @@ type TableInfo struct {
     Comment string
 }
 
-// GetAllTablesWithComments 获取所有表及其注释
+// GetAllTablesWithComments 获取所有表及其注释，合并本地维护的补充描述
 func GetAllTablesWithComments() ([]TableInfo, error) {
+    tables, err := queryAllTables()
+    if err != nil {
+        return nil, err
+    }
+    return applyTableMetadata(tables), nil
+}
+
+// queryAllTables 从 INFORMATION_SCHEMA 查询所有表及其原始注释
+func queryAllTables() ([]TableInfo, error) {
     dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
         config.AppConfig.DBUser,
         config.AppConfig.DBPassword,
@@ -52,7 +62,6 @@ func GetAllTablesWithComments() ([]TableInfo, error) {
         }
         tables = append(tables, TableInfo{Name: name, Comment: comment})
     }
-
     return tables, nil
 }
 
@@ -133,7 +142,7 @@ func GetTableSchemaWithComments(tableName string) ([]map[string]string, error) {
         column := map[string]string{
             "name": name,
             "type": dataType,
-            "comment": comment,
+            "comment": applyColumnMetadata(tableName, []ColumnInfo{{Name: name, Comment: comment}})[0].Comment,
         }
         
         columns = append(columns, column)
@@ -142,6 +151,68 @@ func GetTableSchemaWithComments(tableName string) ([]map[string]string, error) {
     return columns, nil
 }
 
+// GetAllColumnsWithComments 一次性获取库中所有表的字段及注释，按表名分组，合并本地维护的补充描述
+func GetAllColumnsWithComments() (map[string][]ColumnInfo, error) {
+    columns, err := queryAllColumns()
+    if err != nil {
+        return nil, err
+    }
+    for table := range columns {
+        columns[table] = applyColumnMetadata(table, columns[table])
+    }
+    return columns, nil
+}
+
+// queryAllColumns 从 INFORMATION_SCHEMA 查询所有表的字段及原始注释
+func queryAllColumns() (map[string][]ColumnInfo, error) {
+    dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+        config.AppConfig.DBUser,
+        config.AppConfig.DBPassword,
+        config.AppConfig.DBHost,
+        config.AppConfig.DBPort,
+        config.AppConfig.DBName)
+
+    db, err := sql.Open("mysql", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to database: %v", err)
+    }
+    defer db.Close()
+
+    query := `
+        SELECT 
+            TABLE_NAME,
+            COLUMN_NAME,
+            COLUMN_TYPE,
+            COLUMN_COMMENT
+        FROM 
+            INFORMATION_SCHEMA.COLUMNS 
+        WHERE 
+            TABLE_SCHEMA = ?
+        ORDER BY 
+            TABLE_NAME, ORDINAL_POSITION
+    `
+
+    rows, err := db.Query(query, config.AppConfig.DBName)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get columns: %v", err)
+    }
+    defer rows.Close()
+
+    columns := make(map[string][]ColumnInfo)
+    for rows.Next() {
+        var table, name, dataType, comment string
+        if err := rows.Scan(&table, &name, &dataType, &comment); err != nil {
+            return nil, err
+        }
+        columns[table] = append(columns[table], ColumnInfo{Name: name, Type: dataType, Comment: comment})
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error during row iteration: %v", err)
+    }
+    return columns, nil
+}
+
 // GetAllTables 获取所有表
 func GetAllTables() ([]string, error) {
     tables, err := GetAllTablesWithComments()
@@ -159,28 +230,39 @@ func GetAllTables() ([]string, error) {
 
 // ExecuteSQL 执行SQL查询
 func ExecuteSQL(query string) ([]map[string]interface{}, error) {
+    _, results, err := ExecuteSQLContext(context.Background(), query, nil)
+    return results, err
+}
+
+// ExecuteSQLContext 执行SQL查询，可通过ctx取消；onRow不为空时每读取一行回调一次已读取的行数；
+// args 为 ? 占位符绑定的参数，由驱动转义，不拼接到SQL中
+func ExecuteSQLContext(ctx context.Context, query string, onRow func(count int), args ...interface{}) ([]string, []map[string]interface{}, error) {
     dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
         config.AppConfig.DBUser,
         config.AppConfig.DBPassword,
         config.AppConfig.DBHost,
         config.AppConfig.DBPort,
         config.AppConfig.DBName)
+    if len(args) > 0 {
+        // StarRocks 对服务端预处理语句的支持有限，由驱动在客户端完成参数转义
+        dsn += "?interpolateParams=true"
+    }
 
     db, err := sql.Open("mysql", dsn)
     if err != nil {
-        return nil, fmt.Errorf("failed to connect to database: %v", err)
+        return nil, nil, fmt.Errorf("failed to connect to database: %v", err)
     }
     defer db.Close()
 
-    rows, err := db.Query(query)
+    rows, err := db.QueryContext(ctx, query, args...)
     if err != nil {
-        return nil, fmt.Errorf("failed to execute query: %v", err)
+        return nil, nil, fmt.Errorf("failed to execute query: %v", err)
     }
     defer rows.Close()
 
     columns, err := rows.Columns()
     if err != nil {
-        return nil, fmt.Errorf("failed to get column names: %v", err)
+        return nil, nil, fmt.Errorf("failed to get column names: %v", err)
     }
 
     var results []map[string]interface{}
@@ -192,7 +274,7 @@ func ExecuteSQL(query string) ([]map[string]interface{}, error) {
         }
 
         if err := rows.Scan(pointers...); err != nil {
-            return nil, fmt.Errorf("failed to scan row: %v", err)
+            return nil, nil, fmt.Errorf("failed to scan row: %v", err)
         }
 
         row := make(map[string]interface{})
@@ -205,7 +287,14 @@ func ExecuteSQL(query string) ([]map[string]interface{}, error) {
             }
         }
         results = append(results, row)
+        if onRow != nil {
+            onRow(len(results))
+        }
     }
 
-    return results, nil
-}
\ No newline at end of file
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("error during row iteration: %v", err)
+    }
+
+    return columns, results, nil
+}
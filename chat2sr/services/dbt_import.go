@@ -0,0 +1,266 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "chat2sr/config"
+)
+
+var ErrDbtManifestMissing = errors.New("dbt manifest.json not found")
+
+// DbtImportSummary 一次 dbt 导入的统计
+type DbtImportSummary struct {
+    Models         int `json:"models"`
+    Metadata       int `json:"metadata"`
+    Relationships  int `json:"relationships"`
+    AcceptedValues int `json:"accepted_values"`
+}
+
+type dbtNode struct {
+    ResourceType string               `json:"resource_type"`
+    Name         string               `json:"name"`
+    Alias        string               `json:"alias"`
+    Identifier   string               `json:"identifier"`
+    SourceName   string               `json:"source_name"`
+    Description  string               `json:"description"`
+    Tags         []string             `json:"tags"`
+    Columns      map[string]dbtColumn `json:"columns"`
+    ColumnName   string               `json:"column_name"`
+    AttachedNode string               `json:"attached_node"`
+    TestMetadata *struct {
+        Name   string                 `json:"name"`
+        Kwargs map[string]interface{} `json:"kwargs"`
+    } `json:"test_metadata"`
+}
+
+type dbtColumn struct {
+    Name        string   `json:"name"`
+    Description string   `json:"description"`
+    Tags        []string `json:"tags"`
+}
+
+type dbtManifest struct {
+    Nodes   map[string]*dbtNode `json:"nodes"`
+    Sources map[string]*dbtNode `json:"sources"`
+}
+
+type dbtCatalog struct {
+    Nodes   map[string]dbtCatalogNode `json:"nodes"`
+    Sources map[string]dbtCatalogNode `json:"sources"`
+}
+
+type dbtCatalogNode struct {
+    Metadata struct {
+        Name string `json:"name"`
+    } `json:"metadata"`
+}
+
+// dbtContext dbt 导入的关联关系和取值字典，每次导入整体替换
+type dbtContext struct {
+    Relationships  []JoinEdge                     `json:"relationships"`
+    AcceptedValues map[string]map[string][]string `json:"accepted_values"`
+}
+
+var (
+    dbtContextMu     sync.RWMutex
+    dbtContextLoaded bool
+    dbtImported      dbtContext
+
+    dbtRefPattern    = regexp.MustCompile(`ref\(\s*(?:['"][^'"]+['"]\s*,\s*)?['"]([^'"]+)['"]`)
+    dbtSourcePattern = regexp.MustCompile(`source\(\s*['"]([^'"]+)['"]\s*,\s*['"]([^'"]+)['"]`)
+)
+
+func dbtContextPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "dbt_context.json")
+}
+
+// ImportDbt 从 dbt 的 target 目录读取 manifest.json 和 catalog.json（可选），导入描述、标签、
+// relationships 测试（作为关联关系）和 accepted_values 测试（作为取值字典），可重复执行
+func ImportDbt(dir string) (*DbtImportSummary, error) {
+    var manifest dbtManifest
+    manifestPath := filepath.Join(dir, "manifest.json")
+    if _, err := os.Stat(manifestPath); err != nil {
+        return nil, fmt.Errorf("%w: %s", ErrDbtManifestMissing, manifestPath)
+    }
+    if err := loadJSONFile(manifestPath, &manifest); err != nil {
+        return nil, err
+    }
+    var catalog dbtCatalog
+    if err := loadJSONFile(filepath.Join(dir, "catalog.json"), &catalog); err != nil {
+        return nil, err
+    }
+
+    // 模型和数据源对应的 StarRocks 表名，catalog.json 中有实际表名时以它为准
+    relations := make(map[string]string)
+    modelIDs := make(map[string]string)
+    sourceIDs := make(map[string]string)
+    for id, node := range manifest.Nodes {
+        if node.ResourceType != "model" && node.ResourceType != "seed" && node.ResourceType != "snapshot" {
+            continue
+        }
+        relations[id] = firstNonEmpty(catalog.Nodes[id].Metadata.Name, node.Alias, node.Name)
+        modelIDs[node.Name] = id
+    }
+    for id, node := range manifest.Sources {
+        relations[id] = firstNonEmpty(catalog.Sources[id].Metadata.Name, node.Identifier, node.Name)
+        sourceIDs[node.SourceName+"."+node.Name] = id
+    }
+
+    summary := &DbtImportSummary{}
+    imported := make(map[string]*TableMetadata)
+    for id, table := range relations {
+        node := manifest.Nodes[id]
+        if node == nil {
+            node = manifest.Sources[id]
+        }
+        if node.ResourceType == "model" {
+            summary.Models++
+        }
+        meta := &TableMetadata{
+            MetadataEntry: MetadataEntry{Description: strings.TrimSpace(node.Description), Tags: node.Tags},
+            Columns:       make(map[string]*MetadataEntry),
+        }
+        for name, col := range node.Columns {
+            if col.Description == "" && len(col.Tags) == 0 {
+                continue
+            }
+            meta.Columns[firstNonEmpty(col.Name, name)] = &MetadataEntry{Description: strings.TrimSpace(col.Description), Tags: col.Tags}
+        }
+        if meta.Description != "" || len(meta.Tags) > 0 || len(meta.Columns) > 0 {
+            imported[table] = meta
+        }
+    }
+
+    // 解析 ref('model') 或 source('src', 'table') 得到表名
+    resolve := func(expr string) string {
+        if m := dbtRefPattern.FindStringSubmatch(expr); m != nil {
+            return relations[modelIDs[m[1]]]
+        }
+        if m := dbtSourcePattern.FindStringSubmatch(expr); m != nil {
+            return relations[sourceIDs[m[1]+"."+m[2]]]
+        }
+        return ""
+    }
+
+    context := dbtContext{AcceptedValues: make(map[string]map[string][]string)}
+    for _, node := range manifest.Nodes {
+        if node.ResourceType != "test" || node.TestMetadata == nil {
+            continue
+        }
+        table := relations[node.AttachedNode]
+        if table == "" {
+            if model, ok := node.TestMetadata.Kwargs["model"].(string); ok {
+                table = resolve(model)
+            }
+        }
+        column := firstNonEmpty(node.ColumnName, kwargString(node.TestMetadata.Kwargs, "column_name"))
+        if table == "" || column == "" {
+            continue
+        }
+
+        switch node.TestMetadata.Name {
+        case "relationships":
+            target := resolve(kwargString(node.TestMetadata.Kwargs, "to"))
+            field := kwargString(node.TestMetadata.Kwargs, "field")
+            if target == "" || field == "" {
+                continue
+            }
+            context.Relationships = append(context.Relationships, JoinEdge{
+                LeftTable:   table,
+                LeftColumn:  column,
+                RightTable:  target,
+                RightColumn: field,
+                Source:      JoinSourceDbt,
+            })
+        case "accepted_values":
+            values, ok := node.TestMetadata.Kwargs["values"].([]interface{})
+            if !ok || len(values) == 0 {
+                continue
+            }
+            if context.AcceptedValues[table] == nil {
+                context.AcceptedValues[table] = make(map[string][]string)
+            }
+            for _, v := range values {
+                context.AcceptedValues[table][column] = appendUnique(context.AcceptedValues[table][column], fmt.Sprint(v))
+            }
+        }
+    }
+    sort.Slice(context.Relationships, func(i, j int) bool {
+        return context.Relationships[i].key() < context.Relationships[j].key()
+    })
+    summary.Relationships = len(context.Relationships)
+    for _, columns := range context.AcceptedValues {
+        summary.AcceptedValues += len(columns)
+    }
+
+    // 先替换上次导入的描述和标签，成功后再保存关联关系和取值字典；
+    // 写入补充元数据后会延迟重建索引和关联关系图，届时使用新的关联关系
+    count, err := ReplaceDbtMetadata(imported)
+    if err != nil {
+        return nil, err
+    }
+    summary.Metadata = count
+
+    if err := saveJSONFile(dbtContextPath(), context); err != nil {
+        return nil, err
+    }
+    dbtContextMu.Lock()
+    dbtImported = context
+    dbtContextLoaded = true
+    dbtContextMu.Unlock()
+    log.Printf("Imported dbt project from %s: %+v", dir, *summary)
+    return summary, nil
+}
+
+// loadDbtContext 首次访问时从磁盘加载 dbt 导入的关联关系和取值字典
+func loadDbtContext() dbtContext {
+    dbtContextMu.RLock()
+    if dbtContextLoaded {
+        defer dbtContextMu.RUnlock()
+        return dbtImported
+    }
+    dbtContextMu.RUnlock()
+
+    dbtContextMu.Lock()
+    defer dbtContextMu.Unlock()
+    if !dbtContextLoaded {
+        dbtContextLoaded = true
+        if err := loadJSONFile(dbtContextPath(), &dbtImported); err != nil {
+            log.Printf("Error loading dbt context: %s", err.Error())
+        }
+    }
+    return dbtImported
+}
+
+// dbtJoins dbt relationships 测试声明的关联关系
+func dbtJoins() []JoinEdge {
+    return loadDbtContext().Relationships
+}
+
+// AcceptedValues dbt accepted_values 测试声明的字段取值
+func AcceptedValues(table, column string) []string {
+    return loadDbtContext().AcceptedValues[table][column]
+}
+
+func kwargString(kwargs map[string]interface{}, key string) string {
+    if v, ok := kwargs[key].(string); ok {
+        return v
+    }
+    return ""
+}
+
+func firstNonEmpty(values ...string) string {
+    for _, v := range values {
+        if v != "" {
+            return v
+        }
+    }
+    return ""
+}
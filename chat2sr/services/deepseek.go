@@ -19,12 +19,12 @@ func GenerateSQL(userInput string) (string, error) {
     
     // 如果没有从输入中提取到表名，则使用相关性分析获取相关表
     if len(tableNames) == 0 {
-        allTables, err := GetAllTablesWithComments()
+        allTables, err := CatalogTables()
         if err != nil {
             return "", fmt.Errorf("获取表失败: %v", err)
         }
 
-        filteredTables := FilterTablesByKeywords(allTables, userInput)
+        filteredTables := RetrieveTables(allTables, userInput).Tables
 
         if len(filteredTables) == 0 {
             return "", fmt.Errorf("无法确定相关表")
@@ -36,23 +36,39 @@ func GenerateSQL(userInput string) (string, error) {
     }
 
 
-    tableSchemas := make(map[string][]string)
+    // 多表查询时补充桥接表，并限定可用的关联条件
+    tableNames, joins := CurrentJoinGraph().ConnectTables(tableNames)
+
+    // 字段取值示例受token预算限制，超出预算后不再追加
+    sampleBudget := config.AppConfig.PromptSampleTokens
+    // 宽表只列出与问题相关的字段，字段列表受token预算限制。只按原始问题挑选字段，不受表名列表和对话历史中SQL的影响
+    question := extractUserQuestion(userInput)
+    schemaBudget := SchemaTokenBudget(len(tableNames))
+    var schemaDesc strings.Builder
     for _, table := range tableNames {
-        columns, err := GetTableSchema(table)
+        schema, err := CatalogTable(table)
         if err != nil {
             return "", err
         }
-        tableSchemas[table] = columns
-    }
-
-    var schemaDesc strings.Builder
-    for table, columns := range tableSchemas {
+        promptColumns := SelectPromptColumns(schema, question, schemaBudget)
         schemaDesc.WriteString(fmt.Sprintf("%s表字段：\n", table))
-        for _, col := range columns {
-            schemaDesc.WriteString(fmt.Sprintf("- %s\n", col))
+        for _, col := range promptColumns.Columns {
+            line := fmt.Sprintf("- %s %s", col.Name, col.Type)
+            if samples := FormatColumnSamples(table, col.Name); samples != "" {
+                if cost := EstimateTokens(samples); cost <= sampleBudget {
+                    line += " " + samples
+                    sampleBudget -= cost
+                }
+            }
+            schemaDesc.WriteString(line + "\n")
         }
+        schemaDesc.WriteString(FormatOmittedColumns(promptColumns.Omitted))
+        schemaDesc.WriteString(FormatTableDetails(schema.Details))
         schemaDesc.WriteString("\n")
     }
+    schemaDesc.WriteString(FormatJoinConditions(joins))
+    schemaDesc.WriteString(FormatGlossaryDefinitions(MatchGlossary(question)))
+    schemaDesc.WriteString(FormatExamples(SimilarExamples(question, config.AppConfig.ExampleTopK)))
 
     systemPrompt := fmt.Sprintf(`你是一个SQL专家。请严格按照以下数据库表结构生成SQL查询：%s
     要求：
@@ -61,6 +77,13 @@ func GenerateSQL(userInput string) (string, error) {
     3. 只返回SQL语句本身，不要包含任何解释或说明
     4. 不要使用markdown格式
     5. 生成的 SQL 必须与 StarRocks 的语法完全匹配
+    6. 表有分区字段时，必须在WHERE中对分区字段加过滤条件，避免全表扫描
+    7. 如果某个物化视图能满足查询需求，优先查询物化视图而不是基表
+    8. 字段给出了可选值时，过滤条件必须使用其中的实际取值，不要自行翻译或猜测
+    9. 多表关联时，如果给出了允许的关联条件，只能使用其中的关联条件
+    10. 问题中出现业务术语时，必须按照给出的业务术语定义的计算口径和过滤条件生成SQL
+    11. 给出了参考示例时，参照示例中的写法处理日期分区、去重等细节
+    12. 给出了对话历史且当前问题是追问（如"按城市拆分一下"、"只看上海"）时，在上一轮SQL的基础上修改，保留其中的过滤条件和口径
 
     用户查询需求：%s`, schemaDesc.String(), userInput)
 
@@ -120,22 +143,24 @@ func GenerateSQL(userInput string) (string, error) {
         return "", fmt.Errorf("API response contains no choices")
     }
 
-    sql := strings.TrimSpace(response.Choices[0].Message.Content)
-    sql = strings.TrimSpace(sql)
-    sql = strings.TrimSpace(sql)
-    
-    // 移除可能的markdown代码块标记
-    sql = strings.TrimPrefix(sql, "```sql")
-    sql = strings.TrimPrefix(sql, "```")
-    sql = strings.TrimSuffix(sql, "```")
-    
-    // 再次去除空白
-    sql = strings.TrimSpace(sql)
-    
-    return sql, nil
+    return cleanSQLResponse(response.Choices[0].Message.Content), nil
 }
 
 
+// extractUserQuestion 从"用户需求:"行中取出原始问题，没有该行时返回整个输入
+func extractUserQuestion(input string) string {
+    for _, prefix := range []string{"用户需求:", "用户需求："} {
+        if idx := strings.Index(input, prefix); idx != -1 {
+            question := input[idx+len(prefix):]
+            if newLineIdx := strings.Index(question, "\n"); newLineIdx != -1 {
+                question = question[:newLineIdx]
+            }
+            return strings.TrimSpace(question)
+        }
+    }
+    return input
+}
+
 func extractTableNames(input string) []string {
     // 查找"需要使用的表:"后面的内容
     tableSection := ""
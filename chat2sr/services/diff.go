@@ -0,0 +1,153 @@
+package services
+
+import (
+    "fmt"
+    "strings"
+)
+
+// 统一格式差异中每段变更前后保留的上下文行数
+const diffContextLines = 3
+
+type diffOp struct {
+    kind byte // ' ' 相同，'-' 删除，'+' 新增
+    text string
+}
+
+// UnifiedDiff 按行比较两段文本，生成统一格式（unified diff）的差异，没有差异时返回空字符串
+func UnifiedDiff(before, after, fromName, toName string) string {
+    ops := diffLines(splitLines(before), splitLines(after))
+    changed := false
+    for _, op := range ops {
+        if op.kind != ' ' {
+            changed = true
+            break
+        }
+    }
+    if !changed {
+        return ""
+    }
+
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+    for start := 0; start < len(ops); {
+        // 找到下一处变更，连同前后的上下文组成一段
+        for start < len(ops) && ops[start].kind == ' ' {
+            start++
+        }
+        if start >= len(ops) {
+            break
+        }
+        hunkStart := start - diffContextLines
+        if hunkStart < 0 {
+            hunkStart = 0
+        }
+        end := start
+        for end < len(ops) {
+            if ops[end].kind != ' ' {
+                end++
+                continue
+            }
+            // 两处变更之间的相同行不超过两倍上下文时合并为一段
+            next := end
+            for next < len(ops) && ops[next].kind == ' ' {
+                next++
+            }
+            if next < len(ops) && next-end <= 2*diffContextLines {
+                end = next
+                continue
+            }
+            end += diffContextLines
+            if end > len(ops) {
+                end = len(ops)
+            }
+            break
+        }
+
+        oldLine, newLine := 1, 1
+        for _, op := range ops[:hunkStart] {
+            if op.kind != '+' {
+                oldLine++
+            }
+            if op.kind != '-' {
+                newLine++
+            }
+        }
+        oldCount, newCount := 0, 0
+        for _, op := range ops[hunkStart:end] {
+            if op.kind != '+' {
+                oldCount++
+            }
+            if op.kind != '-' {
+                newCount++
+            }
+        }
+        b.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount)))
+        for _, op := range ops[hunkStart:end] {
+            b.WriteByte(op.kind)
+            b.WriteString(op.text + "\n")
+        }
+        start = end
+    }
+    return b.String()
+}
+
+// diffLines 基于最长公共子序列计算逐行的编辑序列
+func diffLines(a, b []string) []diffOp {
+    lcs := make([][]int, len(a)+1)
+    for i := range lcs {
+        lcs[i] = make([]int, len(b)+1)
+    }
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+
+    var ops []diffOp
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        switch {
+        case a[i] == b[j]:
+            ops = append(ops, diffOp{' ', a[i]})
+            i++
+            j++
+        case lcs[i+1][j] >= lcs[i][j+1]:
+            ops = append(ops, diffOp{'-', a[i]})
+            i++
+        default:
+            ops = append(ops, diffOp{'+', b[j]})
+            j++
+        }
+    }
+    for ; i < len(a); i++ {
+        ops = append(ops, diffOp{'-', a[i]})
+    }
+    for ; j < len(b); j++ {
+        ops = append(ops, diffOp{'+', b[j]})
+    }
+    return ops
+}
+
+func splitLines(text string) []string {
+    text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+    if text == "" {
+        return nil
+    }
+    return strings.Split(text, "\n")
+}
+
+func hunkRange(start, count int) string {
+    if count == 0 {
+        return fmt.Sprintf("%d,0", start-1)
+    }
+    if count == 1 {
+        return fmt.Sprintf("%d", start)
+    }
+    return fmt.Sprintf("%d,%d", start, count)
+}
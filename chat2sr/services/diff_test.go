@@ -0,0 +1,42 @@
+package services
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+    tests := []struct {
+        name   string
+        before string
+        after  string
+        want   string
+    }{
+        {"identical", "a\nb\n", "a\nb\n", ""},
+        {
+            "single change",
+            "SELECT a\nFROM t\nWHERE x = 1\n",
+            "SELECT a\nFROM t\nWHERE x = 2\n",
+            "--- v1\n+++ v2\n@@ -1,3 +1,3 @@\n SELECT a\n FROM t\n-WHERE x = 1\n+WHERE x = 2\n",
+        },
+        {
+            "separate hunks",
+            "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n",
+            "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nK\nl\nm\n",
+            "--- v1\n+++ v2\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -8,5 +8,6 @@\n h\n i\n j\n-k\n+K\n l\n+m\n",
+        },
+        {
+            "merged hunks",
+            "a\nb\nc\nd\ne\nf\ng\n",
+            "A\nb\nc\nd\ne\nf\nG\n",
+            "--- v1\n+++ v2\n@@ -1,7 +1,7 @@\n-a\n+A\n b\n c\n d\n e\n f\n-g\n+G\n",
+        },
+        {"from empty", "", "a\nb\n", "--- v1\n+++ v2\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+        {"to empty", "a\n", "", "--- v1\n+++ v2\n@@ -1 +0,0 @@\n-a\n"},
+        {"crlf only", "a\r\nb\r\n", "a\nb", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := UnifiedDiff(tt.before, tt.after, "v1", "v2"); got != tt.want {
+                t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+            }
+        })
+    }
+}
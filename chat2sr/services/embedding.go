@@ -0,0 +1,215 @@
+package services
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "hash/fnv"
+    "io"
+    "math"
+    "net/http"
+    "strings"
+    "unicode"
+    "chat2sr/config"
+)
+
+// EmbeddingProvider 文本向量化服务
+type EmbeddingProvider interface {
+    // Name 用于区分不同的向量空间，名称变化时需要重建索引
+    Name() string
+    Embed(texts []string) ([][]float32, error)
+}
+
+// NewEmbeddingProvider 根据配置创建向量化服务，默认使用本地哈希向量
+func NewEmbeddingProvider() EmbeddingProvider {
+    switch config.AppConfig.EmbeddingProvider {
+    case "openai":
+        return &apiEmbeddingProvider{
+            url:    config.AppConfig.EmbeddingAPIURL,
+            apiKey: config.AppConfig.EmbeddingAPIKey,
+            model:  config.AppConfig.EmbeddingModel,
+        }
+    default:
+        dim := config.AppConfig.EmbeddingDim
+        if dim <= 0 {
+            dim = 256
+        }
+        return &hashEmbeddingProvider{dim: dim}
+    }
+}
+
+// apiEmbeddingProvider 调用 OpenAI 兼容的 /embeddings 接口
+type apiEmbeddingProvider struct {
+    url    string
+    apiKey string
+    model  string
+}
+
+const embeddingBatchSize = 32
+
+func (p *apiEmbeddingProvider) Name() string {
+    return "openai:" + p.model
+}
+
+func (p *apiEmbeddingProvider) Embed(texts []string) ([][]float32, error) {
+    var vectors [][]float32
+    for start := 0; start < len(texts); start += embeddingBatchSize {
+        end := start + embeddingBatchSize
+        if end > len(texts) {
+            end = len(texts)
+        }
+        batch, err := p.embedBatch(texts[start:end])
+        if err != nil {
+            return nil, err
+        }
+        vectors = append(vectors, batch...)
+    }
+    return vectors, nil
+}
+
+func (p *apiEmbeddingProvider) embedBatch(texts []string) ([][]float32, error) {
+    requestJSON, err := json.Marshal(map[string]interface{}{
+        "model": p.model,
+        "input": texts,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal request body: %v", err)
+    }
+
+    req, err := http.NewRequest("POST", p.url, bytes.NewBuffer(requestJSON))
+    if err != nil {
+        return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+    client := &http.Client{}
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("failed to send HTTP request: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        bodyBytes, _ := io.ReadAll(resp.Body)
+        return nil, fmt.Errorf("embedding request failed with status code %d: %s", resp.StatusCode, string(bodyBytes))
+    }
+
+    var response struct {
+        Data []struct {
+            Index     int       `json:"index"`
+            Embedding []float32 `json:"embedding"`
+        } `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+        return nil, fmt.Errorf("failed to decode embedding response: %v", err)
+    }
+    if len(response.Data) != len(texts) {
+        return nil, fmt.Errorf("embedding response contains %d vectors, expected %d", len(response.Data), len(texts))
+    }
+
+    vectors := make([][]float32, len(texts))
+    for _, item := range response.Data {
+        if item.Index < 0 || item.Index >= len(texts) {
+            return nil, fmt.Errorf("embedding response contains invalid index %d", item.Index)
+        }
+        vectors[item.Index] = normalizeVector(item.Embedding)
+    }
+    return vectors, nil
+}
+
+// hashEmbeddingProvider 本地哈希向量：把词和汉字二元组哈希到固定维度，不依赖外部服务，结果确定
+type hashEmbeddingProvider struct {
+    dim int
+}
+
+func (p *hashEmbeddingProvider) Name() string {
+    return fmt.Sprintf("hash:%d", p.dim)
+}
+
+func (p *hashEmbeddingProvider) Embed(texts []string) ([][]float32, error) {
+    vectors := make([][]float32, len(texts))
+    for i, text := range texts {
+        vector := make([]float32, p.dim)
+        for _, feature := range hashFeatures(text) {
+            h := fnv.New32a()
+            h.Write([]byte(feature))
+            sum := h.Sum32()
+            // 最高位决定符号，减少哈希冲突带来的偏差
+            if sum&0x80000000 != 0 {
+                vector[int(sum%uint32(p.dim))] -= 1
+            } else {
+                vector[int(sum%uint32(p.dim))] += 1
+            }
+        }
+        vectors[i] = normalizeVector(vector)
+    }
+    return vectors, nil
+}
+
+// hashFeatures 提取文本特征：英文按单词（下划线拆开），中文取单字和相邻二字
+func hashFeatures(text string) []string {
+    var features []string
+    var word []rune
+    var han []rune
+
+    flushWord := func() {
+        if len(word) > 0 {
+            features = append(features, string(word))
+            word = word[:0]
+        }
+    }
+    flushHan := func() {
+        for i, r := range han {
+            features = append(features, string(r))
+            if i+1 < len(han) {
+                features = append(features, string(han[i:i+2]))
+            }
+        }
+        han = han[:0]
+    }
+
+    for _, r := range strings.ToLower(text) {
+        switch {
+        case unicode.Is(unicode.Han, r):
+            flushWord()
+            han = append(han, r)
+        case unicode.IsLetter(r) || unicode.IsDigit(r):
+            flushHan()
+            word = append(word, r)
+        default:
+            flushWord()
+            flushHan()
+        }
+    }
+    flushWord()
+    flushHan()
+    return features
+}
+
+func normalizeVector(vector []float32) []float32 {
+    var norm float64
+    for _, v := range vector {
+        norm += float64(v) * float64(v)
+    }
+    if norm == 0 {
+        return vector
+    }
+    norm = math.Sqrt(norm)
+    for i := range vector {
+        vector[i] = float32(float64(vector[i]) / norm)
+    }
+    return vector
+}
+
+// cosineSimilarity 计算两个已归一化向量的余弦相似度
+func cosineSimilarity(a, b []float32) float64 {
+    if len(a) != len(b) {
+        return 0
+    }
+    var dot float64
+    for i := range a {
+        dot += float64(a[i]) * float64(b[i])
+    }
+    return dot
+}
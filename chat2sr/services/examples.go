@@ -0,0 +1,384 @@
+package services
+
+import (
+    "errors"
+    "log"
+    "math"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+var (
+    ErrExampleNotFound = errors.New("example not found")
+    ErrExampleInvalid  = errors.New("example requires a question and SQL")
+)
+
+// SQLExample 已验证的问题与SQL示例，生成SQL时作为参考
+type SQLExample struct {
+    ID        string    `json:"id"`
+    Question  string    `json:"question"`
+    SQL       string    `json:"sql"`
+    Notes     string    `json:"notes,omitempty"`
+    Source    string    `json:"source,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// ExampleMatch 与问题相似的示例
+type ExampleMatch struct {
+    SQLExample
+    Score float64 `json:"score"`
+}
+
+type exampleStore struct {
+    mu       sync.Mutex
+    loaded   bool
+    provider EmbeddingProvider
+
+    Provider string                 `json:"provider"`
+    Examples map[string]*SQLExample `json:"examples"`
+}
+
+var examples = &exampleStore{}
+
+func examplesPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "examples.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载示例，向量化服务变化时清空旧向量，调用方需持有锁。
+// 加载失败时不标记为已加载，下次访问重试；写操作需检查返回的错误，避免用空数据覆盖磁盘上的示例
+func (s *exampleStore) ensureLoaded() error {
+    if s.loaded {
+        return nil
+    }
+    if s.provider == nil {
+        s.provider = NewEmbeddingProvider()
+        s.Provider = s.provider.Name()
+    }
+    if s.Examples == nil {
+        s.Examples = make(map[string]*SQLExample)
+    }
+
+    var stored exampleStore
+    if err := loadJSONFile(examplesPath(), &stored); err != nil {
+        log.Printf("Error loading examples: %s", err.Error())
+        return err
+    }
+    s.Examples = make(map[string]*SQLExample)
+    if stored.Examples != nil {
+        s.Examples = stored.Examples
+    }
+    if stored.Provider != s.provider.Name() {
+        for _, example := range s.Examples {
+            example.Embedding = nil
+        }
+    }
+    s.loaded = true
+    return nil
+}
+
+// saveLocked 持久化示例，调用方需持有锁
+func (s *exampleStore) saveLocked() error {
+    return saveJSONFile(examplesPath(), s)
+}
+
+// embedMissing 在锁外为快照中缺少向量的示例计算向量，再写回仍未修改的示例并持久化；
+// 失败时保留为空，检索时退回 BM25
+func (s *exampleStore) embedMissing(snapshot map[string]*SQLExample, provider EmbeddingProvider) {
+    var missing []*SQLExample
+    var texts []string
+    for _, example := range snapshot {
+        if example.Embedding == nil {
+            missing = append(missing, example)
+            texts = append(texts, example.Question)
+        }
+    }
+    if len(missing) == 0 {
+        return
+    }
+    vectors, err := provider.Embed(texts)
+    if err != nil {
+        log.Printf("Error embedding examples: %s", err.Error())
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if !s.loaded {
+        return
+    }
+    for i, example := range missing {
+        example.Embedding = vectors[i]
+        if stored, ok := s.Examples[example.ID]; ok && stored.Embedding == nil && stored.Question == example.Question {
+            stored.Embedding = vectors[i]
+        }
+    }
+    if err := s.saveLocked(); err != nil {
+        log.Printf("Error saving examples: %s", err.Error())
+    }
+}
+
+// ListExamples 列出示例，q 不为空时按问题和SQL过滤
+func ListExamples(q string) []SQLExample {
+    examples.mu.Lock()
+    examples.ensureLoaded()
+    list := make([]SQLExample, 0, len(examples.Examples))
+    for _, example := range examples.Examples {
+        copied := *example
+        copied.Embedding = nil
+        list = append(list, copied)
+    }
+    examples.mu.Unlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].CreatedAt.After(list[j].CreatedAt)
+    })
+
+    q = strings.ToLower(strings.TrimSpace(q))
+    if q == "" {
+        return list
+    }
+    var result []SQLExample
+    for _, example := range list {
+        if strings.Contains(strings.ToLower(example.Question+" "+example.SQL+" "+example.Notes), q) {
+            result = append(result, example)
+        }
+    }
+    return result
+}
+
+// GetExample 获取单个示例
+func GetExample(id string) (*SQLExample, error) {
+    examples.mu.Lock()
+    defer examples.mu.Unlock()
+    examples.ensureLoaded()
+
+    example, ok := examples.Examples[id]
+    if !ok {
+        return nil, ErrExampleNotFound
+    }
+    copied := *example
+    copied.Embedding = nil
+    return &copied, nil
+}
+
+// CreateExample 新增示例
+func CreateExample(example SQLExample) (*SQLExample, error) {
+    example.Question = strings.TrimSpace(example.Question)
+    example.SQL = strings.TrimSpace(example.SQL)
+    if example.Question == "" || example.SQL == "" {
+        return nil, ErrExampleInvalid
+    }
+
+    examples.mu.Lock()
+    defer examples.mu.Unlock()
+    if err := examples.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    example.ID = newID()
+    example.CreatedAt = time.Now()
+    example.UpdatedAt = example.CreatedAt
+    example.Embedding = nil
+    examples.Examples[example.ID] = &example
+    if err := examples.saveLocked(); err != nil {
+        delete(examples.Examples, example.ID)
+        return nil, err
+    }
+    copied := example
+    return &copied, nil
+}
+
+// UpdateExample 修改示例
+func UpdateExample(id string, example SQLExample) (*SQLExample, error) {
+    example.Question = strings.TrimSpace(example.Question)
+    example.SQL = strings.TrimSpace(example.SQL)
+    if example.Question == "" || example.SQL == "" {
+        return nil, ErrExampleInvalid
+    }
+
+    examples.mu.Lock()
+    defer examples.mu.Unlock()
+    if err := examples.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    previous, ok := examples.Examples[id]
+    if !ok {
+        return nil, ErrExampleNotFound
+    }
+    example.ID = id
+    example.Source = previous.Source
+    example.CreatedAt = previous.CreatedAt
+    example.UpdatedAt = time.Now()
+    if example.Question == previous.Question {
+        example.Embedding = previous.Embedding
+    } else {
+        example.Embedding = nil
+    }
+    examples.Examples[id] = &example
+    if err := examples.saveLocked(); err != nil {
+        examples.Examples[id] = previous
+        return nil, err
+    }
+    copied := example
+    copied.Embedding = nil
+    return &copied, nil
+}
+
+// DeleteExample 删除示例
+func DeleteExample(id string) error {
+    examples.mu.Lock()
+    defer examples.mu.Unlock()
+    if err := examples.ensureLoaded(); err != nil {
+        return err
+    }
+
+    previous, ok := examples.Examples[id]
+    if !ok {
+        return ErrExampleNotFound
+    }
+    delete(examples.Examples, id)
+    if err := examples.saveLocked(); err != nil {
+        examples.Examples[id] = previous
+        return err
+    }
+    return nil
+}
+
+// SaveResultAsExample 将执行成功并经过确认的查询结果保存为示例
+func SaveResultAsExample(resultID, question, notes string) (*SQLExample, error) {
+    result, err := LoadResult(resultID)
+    if err != nil {
+        return nil, err
+    }
+    if strings.TrimSpace(question) == "" {
+        question = result.Query
+    }
+    return CreateExample(SQLExample{
+        Question: question,
+        SQL:      result.SQL,
+        Notes:    notes,
+        Source:   "result:" + resultID,
+    })
+}
+
+// SimilarExamples 检索与问题最相似的示例，优先使用向量相似度，向量不可用时使用 BM25
+func SimilarExamples(question string, topK int) []ExampleMatch {
+    if topK <= 0 {
+        return nil
+    }
+
+    // 只在复制示例时持有锁，向量化和打分在锁外进行
+    examples.mu.Lock()
+    examples.ensureLoaded()
+    provider := examples.provider
+    snapshot := make(map[string]*SQLExample, len(examples.Examples))
+    for id, example := range examples.Examples {
+        copied := *example
+        snapshot[id] = &copied
+    }
+    examples.mu.Unlock()
+    if len(snapshot) == 0 {
+        return nil
+    }
+    examples.embedMissing(snapshot, provider)
+
+    var matches []ExampleMatch
+    vectors, err := provider.Embed([]string{question})
+    if err == nil {
+        for _, example := range snapshot {
+            if example.Embedding == nil {
+                continue
+            }
+            score := cosineSimilarity(vectors[0], example.Embedding)
+            if score >= config.AppConfig.ExampleMinScore {
+                matches = append(matches, ExampleMatch{SQLExample: *example, Score: score})
+            }
+        }
+    } else {
+        log.Printf("Error embedding question, using BM25 for examples: %s", err.Error())
+        matches = bm25Examples(question, snapshot)
+    }
+
+    sort.Slice(matches, func(i, j int) bool {
+        if matches[i].Score == matches[j].Score {
+            return matches[i].ID < matches[j].ID
+        }
+        return matches[i].Score > matches[j].Score
+    })
+    if len(matches) > topK {
+        matches = matches[:topK]
+    }
+    for i := range matches {
+        matches[i].Embedding = nil
+    }
+    return matches
+}
+
+// bm25Examples 按示例问题的 BM25 得分排序
+func bm25Examples(question string, all map[string]*SQLExample) []ExampleMatch {
+    terms := ExpandAliases(Tokenize(question))
+    docs := make(map[string]map[string]int, len(all))
+    lengths := make(map[string]int, len(all))
+    docFreq := make(map[string]int)
+    totalLength := 0
+    for id, example := range all {
+        tokens := Tokenize(example.Question)
+        docs[id] = make(map[string]int)
+        for _, token := range tokens {
+            if docs[id][token] == 0 {
+                docFreq[token]++
+            }
+            docs[id][token]++
+        }
+        lengths[id] = len(tokens)
+        totalLength += len(tokens)
+    }
+    if totalLength == 0 {
+        return nil
+    }
+    avgLength := float64(totalLength) / float64(len(all))
+    n := float64(len(all))
+
+    var matches []ExampleMatch
+    for id, example := range all {
+        score := 0.0
+        for _, term := range terms {
+            tf := float64(docs[id][term])
+            if tf == 0 {
+                continue
+            }
+            df := float64(docFreq[term])
+            idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+            norm := 1 - bm25B + bm25B*float64(lengths[id])/avgLength
+            score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+        }
+        if score > 0 {
+            matches = append(matches, ExampleMatch{SQLExample: *example, Score: score})
+        }
+    }
+    return matches
+}
+
+// FormatExamples 生成提示词中的参考示例
+func FormatExamples(matches []ExampleMatch) string {
+    if len(matches) == 0 {
+        return ""
+    }
+    var b strings.Builder
+    b.WriteString("参考示例（已验证的相似问题及SQL，注意其中的分区过滤和去重写法）：\n")
+    for _, match := range matches {
+        b.WriteString("问题: " + match.Question + "\n")
+        if match.Notes != "" {
+            b.WriteString("说明: " + match.Notes + "\n")
+        }
+        b.WriteString("SQL: " + match.SQL + "\n\n")
+    }
+    return b.String()
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "chat2sr/config"
+)
+
+func TestExamplesKeepCorruptFile(t *testing.T) {
+    config.AppConfig.DataDir = t.TempDir()
+    examples = &exampleStore{}
+    path := filepath.Join(config.AppConfig.DataDir, "examples.json")
+    if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := CreateExample(SQLExample{Question: "上月订单量", SQL: "SELECT COUNT(*) FROM orders"}); err == nil {
+        t.Fatal("CreateExample succeeded after a failed load")
+    }
+    if data, _ := os.ReadFile(path); string(data) != "{" {
+        t.Errorf("examples file overwritten: %q", data)
+    }
+}
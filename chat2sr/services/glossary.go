@@ -0,0 +1,358 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+    "unicode/utf8"
+    "chat2sr/config"
+)
+
+var (
+    ErrGlossaryNotFound   = errors.New("glossary entry not found")
+    ErrGlossaryTermExists = errors.New("glossary term already exists")
+    ErrGlossaryInvalid    = errors.New("glossary term is required")
+)
+
+// GlossaryEntry 业务术语，描述术语对应的表、字段和计算口径
+type GlossaryEntry struct {
+    ID          string    `json:"id"`
+    Term        string    `json:"term"`
+    Synonyms    []string  `json:"synonyms"`
+    Description string    `json:"description"`
+    Tables      []string  `json:"tables"`
+    Columns     []string  `json:"columns"`
+    Expression  string    `json:"expression,omitempty"`
+    Filter      string    `json:"filter,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// names 术语本身及其同义词
+func (e *GlossaryEntry) names() []string {
+    return append([]string{e.Term}, e.Synonyms...)
+}
+
+type glossaryStore struct {
+    mu      sync.RWMutex
+    loaded  bool
+    entries map[string]*GlossaryEntry
+}
+
+var glossary = &glossaryStore{}
+
+func glossaryPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "glossary.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载术语表，调用方需持有写锁。
+// 加载失败时不标记为已加载，下次访问重试；写操作需检查返回的错误，避免用空术语表覆盖磁盘上的文件
+func (s *glossaryStore) ensureLoaded() error {
+    if s.loaded {
+        return nil
+    }
+    if s.entries == nil {
+        s.entries = make(map[string]*GlossaryEntry)
+    }
+    var entries []*GlossaryEntry
+    if err := loadJSONFile(glossaryPath(), &entries); err != nil {
+        log.Printf("Error loading glossary: %s", err.Error())
+        return err
+    }
+    s.entries = make(map[string]*GlossaryEntry, len(entries))
+    for _, entry := range entries {
+        s.entries[entry.ID] = entry
+    }
+    s.loaded = true
+    return nil
+}
+
+// snapshot 按术语排序的条目副本
+func (s *glossaryStore) snapshot() []GlossaryEntry {
+    s.mu.Lock()
+    s.ensureLoaded()
+    entries := make([]GlossaryEntry, 0, len(s.entries))
+    for _, entry := range s.entries {
+        entries = append(entries, *entry)
+    }
+    s.mu.Unlock()
+
+    sort.Slice(entries, func(i, j int) bool {
+        return entries[i].Term < entries[j].Term
+    })
+    return entries
+}
+
+// saveLocked 持久化术语表，调用方需持有写锁
+func (s *glossaryStore) saveLocked() error {
+    entries := make([]*GlossaryEntry, 0, len(s.entries))
+    for _, entry := range s.entries {
+        entries = append(entries, entry)
+    }
+    sort.Slice(entries, func(i, j int) bool {
+        return entries[i].ID < entries[j].ID
+    })
+    return saveJSONFile(glossaryPath(), entries)
+}
+
+// checkTermLocked 校验术语和同义词没有被其他条目占用，调用方需持有锁
+func (s *glossaryStore) checkTermLocked(entry *GlossaryEntry) error {
+    if strings.TrimSpace(entry.Term) == "" {
+        return ErrGlossaryInvalid
+    }
+    for _, other := range s.entries {
+        if other.ID == entry.ID {
+            continue
+        }
+        for _, name := range entry.names() {
+            for _, existing := range other.names() {
+                if strings.EqualFold(strings.TrimSpace(name), existing) {
+                    return fmt.Errorf("%w: %s", ErrGlossaryTermExists, name)
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// ListGlossary 列出术语，q 不为空时按术语、同义词和描述过滤
+func ListGlossary(q string) []GlossaryEntry {
+    entries := glossary.snapshot()
+    q = strings.ToLower(strings.TrimSpace(q))
+    if q == "" {
+        return entries
+    }
+
+    var result []GlossaryEntry
+    for _, entry := range entries {
+        text := strings.ToLower(strings.Join(entry.names(), " ") + " " + entry.Description)
+        if strings.Contains(text, q) {
+            result = append(result, entry)
+        }
+    }
+    return result
+}
+
+// GetGlossaryEntry 获取单个术语
+func GetGlossaryEntry(id string) (*GlossaryEntry, error) {
+    glossary.mu.Lock()
+    defer glossary.mu.Unlock()
+    glossary.ensureLoaded()
+
+    entry, ok := glossary.entries[id]
+    if !ok {
+        return nil, ErrGlossaryNotFound
+    }
+    copied := *entry
+    return &copied, nil
+}
+
+// CreateGlossaryEntry 新增术语
+func CreateGlossaryEntry(entry GlossaryEntry) (*GlossaryEntry, error) {
+    glossary.mu.Lock()
+    defer glossary.mu.Unlock()
+    if err := glossary.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    normalizeGlossaryEntry(&entry)
+    entry.ID = newID()
+    if err := glossary.checkTermLocked(&entry); err != nil {
+        return nil, err
+    }
+    entry.CreatedAt = time.Now()
+    entry.UpdatedAt = entry.CreatedAt
+
+    glossary.entries[entry.ID] = &entry
+    if err := glossary.saveLocked(); err != nil {
+        delete(glossary.entries, entry.ID)
+        return nil, err
+    }
+    refreshGlossaryIndexes()
+    copied := entry
+    return &copied, nil
+}
+
+// UpdateGlossaryEntry 修改术语
+func UpdateGlossaryEntry(id string, entry GlossaryEntry) (*GlossaryEntry, error) {
+    glossary.mu.Lock()
+    defer glossary.mu.Unlock()
+    if err := glossary.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    previous, ok := glossary.entries[id]
+    if !ok {
+        return nil, ErrGlossaryNotFound
+    }
+    normalizeGlossaryEntry(&entry)
+    entry.ID = id
+    if err := glossary.checkTermLocked(&entry); err != nil {
+        return nil, err
+    }
+    entry.CreatedAt = previous.CreatedAt
+    entry.UpdatedAt = time.Now()
+
+    glossary.entries[id] = &entry
+    if err := glossary.saveLocked(); err != nil {
+        glossary.entries[id] = previous
+        return nil, err
+    }
+    refreshGlossaryIndexes()
+    copied := entry
+    return &copied, nil
+}
+
+// DeleteGlossaryEntry 删除术语
+func DeleteGlossaryEntry(id string) error {
+    glossary.mu.Lock()
+    defer glossary.mu.Unlock()
+    if err := glossary.ensureLoaded(); err != nil {
+        return err
+    }
+
+    previous, ok := glossary.entries[id]
+    if !ok {
+        return ErrGlossaryNotFound
+    }
+    delete(glossary.entries, id)
+    if err := glossary.saveLocked(); err != nil {
+        glossary.entries[id] = previous
+        return err
+    }
+    return nil
+}
+
+// refreshGlossaryIndexes 术语变化后重建检索索引，使新术语加入分词词典
+func refreshGlossaryIndexes() {
+    if catalog, err := GetCatalog(DefaultDatasource); err == nil {
+        catalog.ApplyMetadata()
+    }
+}
+
+// MatchGlossary 找出问题中出现的术语或同义词
+func MatchGlossary(question string) []GlossaryEntry {
+    questionLower := strings.ToLower(question)
+    var matched []GlossaryEntry
+    for _, entry := range glossary.snapshot() {
+        for _, name := range entry.names() {
+            if containsTerm(questionLower, strings.ToLower(name)) {
+                matched = append(matched, entry)
+                break
+            }
+        }
+    }
+    return matched
+}
+
+// GlossaryTables 问题中出现的术语所对应的表
+func GlossaryTables(entries []GlossaryEntry) []string {
+    var tables []string
+    for _, entry := range entries {
+        for _, table := range entry.Tables {
+            tables = appendUnique(tables, table)
+        }
+    }
+    return tables
+}
+
+// FormatGlossaryDefinitions 生成提示词中的业务术语定义
+func FormatGlossaryDefinitions(entries []GlossaryEntry) string {
+    if len(entries) == 0 {
+        return ""
+    }
+    var b strings.Builder
+    b.WriteString("业务术语定义（以此为准）：\n")
+    for _, entry := range entries {
+        b.WriteString("- " + entry.Term)
+        if len(entry.Synonyms) > 0 {
+            b.WriteString("（又称: " + strings.Join(entry.Synonyms, "、") + "）")
+        }
+        if entry.Description != "" {
+            b.WriteString(": " + entry.Description)
+        }
+        b.WriteString("\n")
+        if entry.Expression != "" {
+            b.WriteString("  计算口径: " + entry.Expression + "\n")
+        }
+        if entry.Filter != "" {
+            b.WriteString("  过滤条件: " + entry.Filter + "\n")
+        }
+        if len(entry.Tables) > 0 {
+            b.WriteString("  相关表: " + strings.Join(entry.Tables, ", ") + "\n")
+        }
+        if len(entry.Columns) > 0 {
+            b.WriteString("  相关字段: " + strings.Join(entry.Columns, ", ") + "\n")
+        }
+    }
+    return b.String()
+}
+
+func normalizeGlossaryEntry(entry *GlossaryEntry) {
+    entry.Term = strings.TrimSpace(entry.Term)
+    entry.Synonyms = trimStrings(entry.Synonyms)
+    entry.Tables = trimStrings(entry.Tables)
+    entry.Columns = trimStrings(entry.Columns)
+    entry.Description = strings.TrimSpace(entry.Description)
+    entry.Expression = strings.TrimSpace(entry.Expression)
+    entry.Filter = strings.TrimSpace(entry.Filter)
+}
+
+// trimStrings 去除空白和空字符串
+func trimStrings(list []string) []string {
+    result := []string{}
+    for _, s := range list {
+        if s = strings.TrimSpace(s); s != "" {
+            result = appendUnique(result, s)
+        }
+    }
+    return result
+}
+
+// containsTerm 判断文本中是否包含术语，英文术语要求前后不是字母或数字，避免 DAU 命中 daughter
+func containsTerm(text, term string) bool {
+    if term == "" {
+        return false
+    }
+    for start := 0; ; {
+        idx := strings.Index(text[start:], term)
+        if idx < 0 {
+            return false
+        }
+        idx += start
+        end := idx + len(term)
+        if !isWordBoundary(text, idx, term, true) || !isWordBoundary(text, end, term, false) {
+            start = idx + 1
+            continue
+        }
+        return true
+    }
+}
+
+func isWordBoundary(text string, pos int, term string, before bool) bool {
+    var neighbor, edge rune
+    if before {
+        if pos == 0 {
+            return true
+        }
+        neighbor, _ = utf8.DecodeLastRuneInString(text[:pos])
+        edge, _ = utf8.DecodeRuneInString(term)
+    } else {
+        if pos >= len(text) {
+            return true
+        }
+        neighbor, _ = utf8.DecodeRuneInString(text[pos:])
+        edge, _ = utf8.DecodeLastRuneInString(term)
+    }
+    // 中文没有词边界，只对英文和数字检查
+    if unicode.Is(unicode.Han, edge) {
+        return true
+    }
+    return !(unicode.IsLetter(neighbor) || unicode.IsDigit(neighbor)) || unicode.Is(unicode.Han, neighbor)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "chat2sr/config"
+)
+
+func TestGlossaryKeepsCorruptFile(t *testing.T) {
+    config.AppConfig.DataDir = t.TempDir()
+    glossary = &glossaryStore{}
+    path := filepath.Join(config.AppConfig.DataDir, "glossary.json")
+    if err := os.WriteFile(path, []byte("[{"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := CreateGlossaryEntry(GlossaryEntry{Term: "GMV", Description: "成交总额"}); err == nil {
+        t.Fatal("CreateGlossaryEntry succeeded after a failed load")
+    }
+    if data, _ := os.ReadFile(path); string(data) != "[{" {
+        t.Errorf("glossary file overwritten: %q", data)
+    }
+
+    // 文件修复后重新加载
+    if err := os.WriteFile(path, []byte("[]"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := CreateGlossaryEntry(GlossaryEntry{Term: "GMV", Description: "成交总额"}); err != nil {
+        t.Errorf("CreateGlossaryEntry after repair: %v", err)
+    }
+}
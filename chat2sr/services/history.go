@@ -0,0 +1,293 @@
+package services
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// 查询历史的状态
+const (
+    HistoryStatusGenerated = "generated"
+    HistoryStatusSucceeded = "succeeded"
+    HistoryStatusFailed    = "failed"
+)
+
+// 历史文件中的记录数超过有效记录数的倍数时重写文件
+const historyCompactRatio = 2
+
+var (
+    ErrHistoryNotFound    = errors.New("history entry not found")
+    ErrHistoryNotVerified = errors.New("only successfully executed queries can be saved as examples")
+)
+
+// HistoryEntry 一次提问或执行的记录
+type HistoryEntry struct {
+    ID         string        `json:"id"`
+    User       string        `json:"user,omitempty"`
+    SessionID  string        `json:"session_id,omitempty"`
+    Question   string        `json:"question,omitempty"`
+    SQL        string        `json:"sql"`
+    Args       []interface{} `json:"args,omitempty"`
+    Tables     []string      `json:"tables,omitempty"`
+    Source     string        `json:"source,omitempty"`
+    Status     string        `json:"status"`
+    Error      string        `json:"error,omitempty"`
+    DurationMs int64         `json:"duration_ms,omitempty"`
+    RowCount   int           `json:"row_count,omitempty"`
+    ResultID   string        `json:"result_id,omitempty"`
+    RerunOf    string        `json:"rerun_of,omitempty"`
+    CreatedAt  time.Time     `json:"created_at"`
+    UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// HistoryFilter 查询历史的过滤条件，日期范围包含 From 不包含 To
+type HistoryFilter struct {
+    Query  string
+    User   string
+    Table  string
+    Status string
+    From   time.Time
+    To     time.Time
+}
+
+// historyStore 查询历史以追加方式写入 JSON Lines 文件，同一ID的后一条记录覆盖前一条，
+// 避免每次记录都重写整个文件
+type historyStore struct {
+    mu      sync.Mutex
+    loaded  bool
+    entries map[string]*HistoryEntry
+    lines   int
+}
+
+var history = &historyStore{}
+
+func historyPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "history.jsonl")
+}
+
+// ensureLoaded 首次访问时回放历史文件，丢弃过期记录，调用方需持有锁
+func (s *historyStore) ensureLoaded() {
+    if s.loaded {
+        return
+    }
+    s.loaded = true
+    s.entries = make(map[string]*HistoryEntry)
+
+    file, err := os.Open(historyPath())
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("Error loading query history: %s", err.Error())
+        }
+        return
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    for scanner.Scan() {
+        var entry HistoryEntry
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.ID == "" {
+            continue
+        }
+        s.entries[entry.ID] = &entry
+        s.lines++
+    }
+    if err := scanner.Err(); err != nil {
+        log.Printf("Error reading query history: %s", err.Error())
+    }
+
+    if retention := config.AppConfig.HistoryRetention; retention > 0 {
+        cutoff := time.Now().Add(-retention)
+        for id, entry := range s.entries {
+            if entry.CreatedAt.Before(cutoff) {
+                delete(s.entries, id)
+            }
+        }
+    }
+    if s.lines > historyCompactRatio*len(s.entries) {
+        if err := s.compactLocked(); err != nil {
+            log.Printf("Error compacting query history: %s", err.Error())
+        }
+    }
+}
+
+// appendLocked 追加一条记录，调用方需持有锁
+func (s *historyStore) appendLocked(entry *HistoryEntry) error {
+    data, err := json.Marshal(entry)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(historyPath()), 0755); err != nil {
+        return err
+    }
+    file, err := os.OpenFile(historyPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+    if _, err := file.Write(append(data, '\n')); err != nil {
+        return err
+    }
+    s.lines++
+    return nil
+}
+
+// compactLocked 只保留每条记录的最新版本重写文件，调用方需持有锁
+func (s *historyStore) compactLocked() error {
+    list := make([]*HistoryEntry, 0, len(s.entries))
+    for _, entry := range s.entries {
+        list = append(list, entry)
+    }
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].CreatedAt.Before(list[j].CreatedAt)
+    })
+
+    var b strings.Builder
+    for _, entry := range list {
+        data, err := json.Marshal(entry)
+        if err != nil {
+            return err
+        }
+        b.Write(data)
+        b.WriteByte('\n')
+    }
+    if err := writeFileAtomic(historyPath(), []byte(b.String())); err != nil {
+        return err
+    }
+    s.lines = len(list)
+    return nil
+}
+
+// RecordHistory 新增一条历史记录，返回记录ID
+func RecordHistory(entry HistoryEntry) (string, error) {
+    history.mu.Lock()
+    defer history.mu.Unlock()
+    history.ensureLoaded()
+
+    entry.ID = newID()
+    entry.User = strings.TrimSpace(entry.User)
+    entry.CreatedAt = time.Now()
+    entry.UpdatedAt = entry.CreatedAt
+    if err := history.appendLocked(&entry); err != nil {
+        return "", err
+    }
+    history.entries[entry.ID] = &entry
+    return entry.ID, nil
+}
+
+// RecordExecution 记录SQL的执行结果
+func RecordExecution(id string, resultID string, rowCount int, duration time.Duration, execErr error) error {
+    history.mu.Lock()
+    defer history.mu.Unlock()
+    history.ensureLoaded()
+
+    previous, ok := history.entries[id]
+    if !ok {
+        return ErrHistoryNotFound
+    }
+    entry := *previous
+    entry.DurationMs = duration.Milliseconds()
+    entry.UpdatedAt = time.Now()
+    if execErr != nil {
+        entry.Status = HistoryStatusFailed
+        entry.Error = execErr.Error()
+    } else {
+        entry.Status = HistoryStatusSucceeded
+        entry.Error = ""
+        entry.ResultID = resultID
+        entry.RowCount = rowCount
+    }
+    if err := history.appendLocked(&entry); err != nil {
+        return err
+    }
+    history.entries[id] = &entry
+    return nil
+}
+
+// GetHistoryEntry 获取单条历史记录
+func GetHistoryEntry(id string) (*HistoryEntry, error) {
+    history.mu.Lock()
+    defer history.mu.Unlock()
+    history.ensureLoaded()
+
+    entry, ok := history.entries[id]
+    if !ok {
+        return nil, ErrHistoryNotFound
+    }
+    copied := *entry
+    return &copied, nil
+}
+
+// SearchHistory 按条件检索历史记录，按时间倒序返回。关键词分词后每个词都需出现在问题或SQL中
+func SearchHistory(filter HistoryFilter) []HistoryEntry {
+    terms := Tokenize(filter.Query)
+
+    history.mu.Lock()
+    history.ensureLoaded()
+    var list []HistoryEntry
+    for _, entry := range history.entries {
+        if filter.User != "" && entry.User != filter.User {
+            continue
+        }
+        if filter.Status != "" && entry.Status != filter.Status {
+            continue
+        }
+        if filter.Table != "" && !containsString(entry.Tables, filter.Table) {
+            continue
+        }
+        if !filter.From.IsZero() && entry.CreatedAt.Before(filter.From) {
+            continue
+        }
+        if !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To) {
+            continue
+        }
+        if len(terms) > 0 && !matchesAllTerms(strings.ToLower(entry.Question+" "+entry.SQL), terms) {
+            continue
+        }
+        list = append(list, *entry)
+    }
+    history.mu.Unlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].CreatedAt.After(list[j].CreatedAt)
+    })
+    return list
+}
+
+// SaveHistoryAsExample 将执行成功的历史查询保存为示例，未执行或执行失败的记录返回 ErrHistoryNotVerified
+func SaveHistoryAsExample(id, question, notes string) (*SQLExample, error) {
+    entry, err := GetHistoryEntry(id)
+    if err != nil {
+        return nil, err
+    }
+    if entry.Status != HistoryStatusSucceeded {
+        return nil, ErrHistoryNotVerified
+    }
+    if strings.TrimSpace(question) == "" {
+        question = entry.Question
+    }
+    return CreateExample(SQLExample{
+        Question: question,
+        SQL:      entry.SQL,
+        Notes:    notes,
+        Source:   "history:" + id,
+    })
+}
+
+func matchesAllTerms(text string, terms []string) bool {
+    for _, term := range terms {
+        if !strings.Contains(text, term) {
+            return false
+        }
+    }
+    return true
+}
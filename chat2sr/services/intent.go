@@ -0,0 +1,260 @@
+package services
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "regexp"
+    "strings"
+    "chat2sr/config"
+)
+
+// 问题的路由
+const (
+    IntentData        = "data"
+    IntentMetadata    = "metadata"
+    IntentDefinition  = "definition"
+    IntentUnsupported = "unsupported"
+)
+
+const (
+    // 元数据问题最多列出的表数
+    intentMaxTables = 10
+    // 元数据问题中单张表最多列出的字段数
+    intentMaxColumns = 50
+)
+
+var (
+    // 寒暄
+    chitchatPattern = regexp.MustCompile(`^(你好|您好|嗨|哈喽|在吗|在不在|谢谢|多谢|感谢|再见|拜拜|早上好|晚上好|你是谁|你叫什么|你能做什么|(?i)hi|hello|hey|thanks|thank you|bye)[\s!！。.,，?？~～]*$`)
+    // 询问表结构、字段、表说明等元数据，其中的表、字段、列还需是分词后完整的词
+    metadataPattern = regexp.MustCompile(`(有|存在)?哪些(表|字段|列|数据表)|哪(张|个)表|什么表|表结构|表的(说明|含义|字段)|字段(有哪些|是什么|的含义|说明)|(表|字段|列)在哪|(?i)\b(which|what)\s+tables?\b|\b(table|database)\s+schema\b|\bschema\s+(of|for)\b|\bcolumns?\s+(of|in)\b`)
+    // 元数据问题中的名词，"列车"、"表单"等词中的列、表不算
+    metadataNouns = []string{"表", "字段", "列"}
+    // 询问指标或术语的定义和口径
+    definitionPattern = regexp.MustCompile(`什么意思|什么是|的定义|怎么(算|计算|定义)的|是怎么(算|计算|定义)|如何(计算|定义)|计算口径|的口径|(?i)\b(what\s+does\s+\w+\s+mean|what\s+is\s+meant\s+by|definition\s+of|how\s+is\s+.+\s+(calculated|defined))\b`)
+)
+
+// IntentRoute 问题的分类结果
+type IntentRoute struct {
+    Route  string `json:"route"`
+    Reason string `json:"reason,omitempty"`
+}
+
+// IntentAnswer 非数据类问题的回答
+type IntentAnswer struct {
+    Route    string             `json:"route"`
+    Answer   string             `json:"answer"`
+    Tables   []TableSchema      `json:"tables,omitempty"`
+    Glossary []GlossaryEntry    `json:"glossary,omitempty"`
+    Metrics  []MetricDefinition `json:"metrics,omitempty"`
+}
+
+// ClassifyIntent 判断问题应该走哪条路由：数据查询、元数据、术语定义或不支持的请求。
+// 先按规则判断，规则无法判断时按数据查询处理，开启 INTENT_LLM_ENABLED 后让模型分类；追问默认是数据查询，不调用模型
+func ClassifyIntent(question string, followUp bool) IntentRoute {
+    question = strings.TrimSpace(question)
+    if !config.AppConfig.IntentRouterEnabled {
+        return IntentRoute{Route: IntentData, Reason: "router disabled"}
+    }
+    if chitchatPattern.MatchString(question) {
+        return IntentRoute{Route: IntentUnsupported, Reason: "chitchat"}
+    }
+    if isMetadataQuestion(question) {
+        return IntentRoute{Route: IntentMetadata, Reason: "rule"}
+    }
+    if definitionPattern.MatchString(question) && len(matchDefinitions(question)) > 0 {
+        return IntentRoute{Route: IntentDefinition, Reason: "rule"}
+    }
+    if followUp || !config.AppConfig.IntentLLMEnabled {
+        return IntentRoute{Route: IntentData, Reason: "default"}
+    }
+
+    route, err := classifyIntentWithLLM(question)
+    if err != nil {
+        log.Printf("Error classifying question intent, treating as data query: %s", err.Error())
+        return IntentRoute{Route: IntentData, Reason: "default"}
+    }
+    return route
+}
+
+// isMetadataQuestion 问题是否符合元数据问题的句式，且句式中的表、字段、列在分词结果中是完整的词
+func isMetadataQuestion(question string) bool {
+    matches := metadataPattern.FindAllString(question, -1)
+    if len(matches) == 0 {
+        return false
+    }
+    tokens := make(map[string]bool)
+    for _, token := range Tokenize(question) {
+        tokens[token] = true
+    }
+    for _, match := range matches {
+        hasNoun := false
+        for _, noun := range metadataNouns {
+            if strings.Contains(match, noun) {
+                hasNoun = true
+                if tokens[noun] {
+                    return true
+                }
+            }
+        }
+        // 英文句式
+        if !hasNoun {
+            return true
+        }
+    }
+    return false
+}
+
+func classifyIntentWithLLM(question string) (IntentRoute, error) {
+    prompt := fmt.Sprintf(`你是数据助手的问题分类器，请判断用户输入属于哪一类：
+- data: 需要查询数据库中的数据才能回答，如统计、明细、趋势、排名
+- metadata: 询问有哪些表、表或字段的含义、数据在哪张表
+- definition: 询问某个指标或业务术语的定义、计算口径
+- unsupported: 寒暄、闲聊，或与数据查询无关的请求（如写文章、翻译、修改数据）
+只返回一个JSON对象，不要包含其他内容，格式如下：
+{"route": "data|metadata|definition|unsupported", "reason": "简短理由"}
+
+用户输入: %s`, question)
+
+    response, err := ProcessQuery(prompt)
+    if err != nil {
+        return IntentRoute{}, err
+    }
+    var route IntentRoute
+    if err := json.Unmarshal([]byte(extractJSONObject(response)), &route); err != nil {
+        return IntentRoute{}, fmt.Errorf("failed to parse intent: %v", err)
+    }
+    switch route.Route {
+    case IntentData, IntentMetadata, IntentDefinition, IntentUnsupported:
+        return route, nil
+    }
+    return IntentRoute{}, fmt.Errorf("unknown intent route: %s", route.Route)
+}
+
+// AnswerIntent 回答非数据类的问题：元数据问题查表结构目录，定义问题查术语表和指标层，其余礼貌地拒绝
+func AnswerIntent(route, question string) (*IntentAnswer, error) {
+    switch route {
+    case IntentMetadata:
+        return answerMetadata(question)
+    case IntentDefinition:
+        return answerDefinition(question), nil
+    default:
+        return &IntentAnswer{
+            Route:  IntentUnsupported,
+            Answer: "抱歉，我是数据查询助手，只能回答与数据相关的问题，例如查询统计数据、查找相关的表和字段、解释指标口径。请换一种方式描述您的数据需求。",
+        }, nil
+    }
+}
+
+// answerMetadata 问题中提到具体的表时列出其字段，否则检索相关的表
+func answerMetadata(question string) (*IntentAnswer, error) {
+    allTables, err := CatalogTables()
+    if err != nil {
+        return nil, err
+    }
+    answer := &IntentAnswer{Route: IntentMetadata}
+    questionLower := strings.ToLower(question)
+    for _, table := range allTables {
+        if !containsTerm(questionLower, strings.ToLower(table.Name)) {
+            continue
+        }
+        schema, err := CatalogTable(table.Name)
+        if err != nil {
+            continue
+        }
+        described := TableSchema{Name: schema.Name, Comment: firstNonEmpty(schema.Comment, table.Comment)}
+        described.Columns = schema.Columns
+        if len(described.Columns) > intentMaxColumns {
+            described.Columns = described.Columns[:intentMaxColumns]
+        }
+        answer.Tables = append(answer.Tables, described)
+    }
+
+    var b strings.Builder
+    if len(answer.Tables) > 0 {
+        for _, table := range answer.Tables {
+            b.WriteString(fmt.Sprintf("表 %s", table.Name))
+            if table.Comment != "" {
+                b.WriteString(fmt.Sprintf("（%s）", table.Comment))
+            }
+            b.WriteString(" 的字段：\n")
+            for _, col := range table.Columns {
+                line := fmt.Sprintf("- %s %s", col.Name, col.Type)
+                if col.Comment != "" {
+                    line += " " + col.Comment
+                }
+                b.WriteString(line + "\n")
+            }
+        }
+        answer.Answer = strings.TrimSpace(b.String())
+        return answer, nil
+    }
+
+    for _, table := range RetrieveTables(allTables, question).Tables {
+        if len(answer.Tables) >= intentMaxTables {
+            break
+        }
+        answer.Tables = append(answer.Tables, TableSchema{Name: table.Name, Comment: table.Comment})
+    }
+    if len(answer.Tables) == 0 {
+        answer.Answer = "没有找到与问题相关的表，可以换个关键词试试。"
+        return answer, nil
+    }
+    b.WriteString("可能相关的表：\n")
+    for _, table := range answer.Tables {
+        line := "- " + table.Name
+        if table.Comment != "" {
+            line += "：" + table.Comment
+        }
+        b.WriteString(line + "\n")
+    }
+    answer.Answer = strings.TrimSpace(b.String())
+    return answer, nil
+}
+
+// answerDefinition 从术语表和指标层中查找问题提到的术语和指标
+func answerDefinition(question string) *IntentAnswer {
+    answer := &IntentAnswer{Route: IntentDefinition, Glossary: MatchGlossary(question)}
+    if layer, err := CurrentSemanticLayer(); err == nil {
+        answer.Metrics = layer.MatchMetrics(question)
+    }
+
+    var b strings.Builder
+    for _, entry := range answer.Glossary {
+        b.WriteString(fmt.Sprintf("%s：%s", entry.Term, entry.Description))
+        if entry.Expression != "" {
+            b.WriteString(fmt.Sprintf("\n  计算方式: %s", entry.Expression))
+        }
+        if entry.Filter != "" {
+            b.WriteString(fmt.Sprintf("\n  过滤条件: %s", entry.Filter))
+        }
+        b.WriteString("\n")
+    }
+    for _, metric := range answer.Metrics {
+        b.WriteString(fmt.Sprintf("%s：%s\n  计算方式: %s（%s）", firstNonEmpty(metric.Label, metric.Name), metric.Description, metric.Expression, metric.Table))
+        if len(metric.Filters) > 0 {
+            b.WriteString(fmt.Sprintf("\n  过滤条件: %s", strings.Join(metric.Filters, " AND ")))
+        }
+        b.WriteString("\n")
+    }
+    answer.Answer = strings.TrimSpace(b.String())
+    if answer.Answer == "" {
+        answer.Answer = "术语表和指标定义中没有找到相关的说明，可以在术语表中补充该术语的定义。"
+    }
+    return answer
+}
+
+// matchDefinitions 问题提到的术语和指标名称
+func matchDefinitions(question string) []string {
+    var names []string
+    for _, entry := range MatchGlossary(question) {
+        names = append(names, entry.Term)
+    }
+    if layer, err := CurrentSemanticLayer(); err == nil {
+        for _, metric := range layer.MatchMetrics(question) {
+            names = append(names, metric.Name)
+        }
+    }
+    return names
+}
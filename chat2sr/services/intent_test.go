@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestIsMetadataQuestion(t *testing.T) {
+    tests := []struct {
+        question string
+        want     bool
+    }{
+        {"有哪些表", true},
+        {"订单表有哪些字段", true},
+        {"哪张表有用户手机号", true},
+        {"哪个表存了退款", true},
+        {"user_id字段在哪", true},
+        {"用户表的表结构", true},
+        {"which tables have revenue", true},
+        {"columns of orders", true},
+        {"哪些列车晚点超过1小时", false},
+        {"哪个表单提交量最高", false},
+        {"按schema统计", false},
+        {"上个月订单金额", false},
+    }
+    for _, tt := range tests {
+        if got := isMetadataQuestion(tt.question); got != tt.want {
+            t.Errorf("isMetadataQuestion(%q) = %v, want %v", tt.question, got, tt.want)
+        }
+    }
+}
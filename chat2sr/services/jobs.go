@@ -0,0 +1,303 @@
+package services
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "log"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// 任务状态
+const (
+    JobStatusQueued    = "queued"
+    JobStatusRunning   = "running"
+    JobStatusSucceeded = "succeeded"
+    JobStatusFailed    = "failed"
+    JobStatusCancelled = "cancelled"
+)
+
+var (
+    ErrJobNotFound  = errors.New("job not found")
+    ErrJobQueueFull = errors.New("job queue is full")
+    ErrJobNotDone   = errors.New("job has not finished")
+    ErrJobFinished  = errors.New("job has already finished")
+)
+
+// Job 异步查询任务
+type Job struct {
+    ID          string
+    SQL         string
+    User        string
+    Status      string
+    RowsFetched int
+    Error       string
+    CreatedAt   time.Time
+    StartedAt   time.Time
+    FinishedAt  time.Time
+    ResultID    string
+
+    ctx     context.Context
+    cancel  context.CancelFunc
+}
+
+// JobStatus 任务状态快照，用于对外返回
+type JobStatus struct {
+    ID          string     `json:"id"`
+    Status      string     `json:"status"`
+    Progress    float64    `json:"progress"`
+    RowsFetched int        `json:"rows_fetched"`
+    Error       string     `json:"error,omitempty"`
+    ResultID    string     `json:"result_id,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+    StartedAt   *time.Time `json:"started_at,omitempty"`
+    FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+type jobManager struct {
+    mu    sync.Mutex
+    jobs  map[string]*Job
+    queue chan *Job
+}
+
+var jobs *jobManager
+
+// StartJobWorkers 启动固定数量的查询worker以及过期结果清理
+func StartJobWorkers() {
+    workers := config.AppConfig.JobWorkers
+    if workers <= 0 {
+        workers = 1
+    }
+    queueSize := config.AppConfig.JobQueueSize
+    if queueSize <= 0 {
+        queueSize = 1
+    }
+
+    jobs = &jobManager{
+        jobs:  make(map[string]*Job),
+        queue: make(chan *Job, queueSize),
+    }
+
+    for i := 0; i < workers; i++ {
+        go jobs.work()
+    }
+    // 任务信息与结果文件保留同样的时长，任务过期前总能取到结果
+    go jobs.cleanup(config.AppConfig.ResultRetention)
+
+    log.Printf("Started %d job workers (queue size %d, retention %s)", workers, queueSize, config.AppConfig.ResultRetention)
+}
+
+// SubmitJob 提交SQL异步执行，返回任务ID
+func SubmitJob(query, user string) (string, error) {
+    ctx, cancel := context.WithCancel(context.Background())
+    job := &Job{
+        ID:        newID(),
+        SQL:       query,
+        User:      user,
+        Status:    JobStatusQueued,
+        CreatedAt: time.Now(),
+        ctx:       ctx,
+        cancel:    cancel,
+    }
+
+    jobs.mu.Lock()
+    jobs.jobs[job.ID] = job
+    jobs.mu.Unlock()
+
+    select {
+    case jobs.queue <- job:
+    default:
+        cancel()
+        jobs.mu.Lock()
+        delete(jobs.jobs, job.ID)
+        jobs.mu.Unlock()
+        return "", ErrJobQueueFull
+    }
+
+    return job.ID, nil
+}
+
+// GetJobStatus 获取任务状态
+func GetJobStatus(id string) (JobStatus, error) {
+    jobs.mu.Lock()
+    defer jobs.mu.Unlock()
+
+    job, ok := jobs.jobs[id]
+    if !ok {
+        return JobStatus{}, ErrJobNotFound
+    }
+    return job.snapshot(), nil
+}
+
+// GetJobResult 分页获取已成功任务的结果，page 从1开始
+func GetJobResult(id string, page, pageSize int) (ResultPage, error) {
+    jobs.mu.Lock()
+    job, ok := jobs.jobs[id]
+    if !ok {
+        jobs.mu.Unlock()
+        return ResultPage{}, ErrJobNotFound
+    }
+    status, resultID := job.Status, job.ResultID
+    jobs.mu.Unlock()
+
+    if status != JobStatusSucceeded {
+        return ResultPage{}, ErrJobNotDone
+    }
+    return GetResultPage(resultID, page, pageSize, "", false)
+}
+
+// CancelJob 取消排队中或运行中的任务
+func CancelJob(id string) error {
+    jobs.mu.Lock()
+    defer jobs.mu.Unlock()
+
+    job, ok := jobs.jobs[id]
+    if !ok {
+        return ErrJobNotFound
+    }
+    if job.Status != JobStatusQueued && job.Status != JobStatusRunning {
+        return ErrJobFinished
+    }
+
+    job.Status = JobStatusCancelled
+    job.FinishedAt = time.Now()
+    job.cancel()
+    return nil
+}
+
+func (m *jobManager) work() {
+    for job := range m.queue {
+        m.mu.Lock()
+        if job.Status != JobStatusQueued {
+            // 排队期间已被取消
+            m.mu.Unlock()
+            continue
+        }
+        job.Status = JobStatusRunning
+        job.StartedAt = time.Now()
+        m.mu.Unlock()
+
+        log.Printf("Job %s started: %s", job.ID, job.SQL)
+        columns, results, err := ExecuteSQLContext(job.ctx, job.SQL, func(count int) {
+            m.mu.Lock()
+            job.RowsFetched = count
+            m.mu.Unlock()
+        })
+
+        // 结果落盘后再更新状态，保证成功的任务一定可以取到结果
+        resultID := ""
+        if err == nil {
+            resultID, err = SaveResult("", job.SQL, columns, results)
+            go LearnJoins(job.SQL)
+        }
+
+        m.mu.Lock()
+        if job.Status == JobStatusCancelled {
+            log.Printf("Job %s cancelled", job.ID)
+        } else if err != nil {
+            job.Status = JobStatusFailed
+            job.Error = err.Error()
+            job.FinishedAt = time.Now()
+            log.Printf("Job %s failed: %s", job.ID, err.Error())
+        } else {
+            job.Status = JobStatusSucceeded
+            job.ResultID = resultID
+            job.RowsFetched = len(results)
+            job.FinishedAt = time.Now()
+            log.Printf("Job %s succeeded with %d rows in %s", job.ID, len(results), job.FinishedAt.Sub(job.StartedAt))
+        }
+        entry := HistoryEntry{
+            User:       job.User,
+            SQL:        job.SQL,
+            Tables:     ExtractSQLTables(job.SQL),
+            Source:     "job",
+            Status:     HistoryStatusSucceeded,
+            Error:      job.Error,
+            DurationMs: job.FinishedAt.Sub(job.StartedAt).Milliseconds(),
+            RowCount:   job.RowsFetched,
+            ResultID:   job.ResultID,
+        }
+        if job.Status == JobStatusFailed {
+            entry.Status = HistoryStatusFailed
+        }
+        recordJob := job.Status != JobStatusCancelled
+        m.mu.Unlock()
+
+        if recordJob {
+            if _, err := RecordHistory(entry); err != nil {
+                log.Printf("Error recording history for job %s: %s", job.ID, err.Error())
+            }
+        }
+
+        job.cancel()
+    }
+}
+
+// cleanup 定期移除超过保留期的已结束任务
+func (m *jobManager) cleanup(retention time.Duration) {
+    interval := retention / 2
+    if interval < time.Minute {
+        interval = time.Minute
+    }
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for range ticker.C {
+        now := time.Now()
+        m.mu.Lock()
+        for id, job := range m.jobs {
+            if job.FinishedAt.IsZero() {
+                continue
+            }
+            if now.Sub(job.FinishedAt) > retention {
+                delete(m.jobs, id)
+                log.Printf("Job %s expired and removed", id)
+            }
+        }
+        m.mu.Unlock()
+    }
+}
+
+// snapshot 生成任务状态快照，调用方需持有锁
+func (j *Job) snapshot() JobStatus {
+    status := JobStatus{
+        ID:          j.ID,
+        Status:      j.Status,
+        RowsFetched: j.RowsFetched,
+        Error:       j.Error,
+        ResultID:    j.ResultID,
+        CreatedAt:   j.CreatedAt,
+    }
+
+    // 数据库不提供执行进度，按阶段估算：排队0，执行中0.5，结束1
+    switch j.Status {
+    case JobStatusQueued:
+        status.Progress = 0
+    case JobStatusRunning:
+        status.Progress = 0.5
+    default:
+        status.Progress = 1
+    }
+
+    if !j.StartedAt.IsZero() {
+        startedAt := j.StartedAt
+        status.StartedAt = &startedAt
+    }
+    if !j.FinishedAt.IsZero() {
+        finishedAt := j.FinishedAt
+        status.FinishedAt = &finishedAt
+    }
+    return status
+}
+
+func newID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return time.Now().Format("20060102150405.000000000")
+    }
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,553 @@
+package services
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "chat2sr/config"
+    "gopkg.in/yaml.v3"
+)
+
+// 关联关系的来源
+const (
+    JoinSourceNaming   = "naming"
+    JoinSourceDeclared = "declared"
+    JoinSourceHistory  = "history"
+    JoinSourceDbt      = "dbt"
+)
+
+// 补充桥接表时允许的最大路径长度
+const maxJoinPathLength = 3
+
+// JoinEdge 两张表之间的一条关联条件
+type JoinEdge struct {
+    LeftTable   string `json:"left_table"`
+    LeftColumn  string `json:"left_column"`
+    RightTable  string `json:"right_table"`
+    RightColumn string `json:"right_column"`
+    Source      string `json:"source"`
+}
+
+// Condition 关联条件的SQL写法
+func (e JoinEdge) Condition() string {
+    return fmt.Sprintf("%s.%s = %s.%s", e.LeftTable, e.LeftColumn, e.RightTable, e.RightColumn)
+}
+
+func (e JoinEdge) key() string {
+    left := e.LeftTable + "." + e.LeftColumn
+    right := e.RightTable + "." + e.RightColumn
+    if left > right {
+        left, right = right, left
+    }
+    return left + "=" + right
+}
+
+// other 返回边的另一端表
+func (e JoinEdge) other(table string) string {
+    if e.LeftTable == table {
+        return e.RightTable
+    }
+    return e.LeftTable
+}
+
+// JoinGraph 表之间的关联关系图
+type JoinGraph struct {
+    edges map[string][]JoinEdge
+}
+
+var (
+    joinGraphMu sync.RWMutex
+    joinGraph   = &JoinGraph{edges: make(map[string][]JoinEdge)}
+
+    learnedJoinsMu sync.Mutex
+    learnedJoins   map[string]JoinEdge
+)
+
+// declaredRelationships 关联关系配置文件格式
+type declaredRelationships struct {
+    Relationships []struct {
+        From string `yaml:"from"`
+        To   string `yaml:"to"`
+    } `yaml:"relationships"`
+}
+
+// UpdateJoinGraph 根据命名约定、配置文件和历史查询重建关联关系图
+func UpdateJoinGraph(catalog *SchemaCatalog) {
+    graph := &JoinGraph{edges: make(map[string][]JoinEdge)}
+    seen := make(map[string]bool)
+    add := func(edge JoinEdge) {
+        if edge.LeftTable == edge.RightTable || seen[edge.key()] {
+            return
+        }
+        if !catalogHasColumn(catalog, edge.LeftTable, edge.LeftColumn) || !catalogHasColumn(catalog, edge.RightTable, edge.RightColumn) {
+            return
+        }
+        seen[edge.key()] = true
+        graph.edges[edge.LeftTable] = append(graph.edges[edge.LeftTable], edge)
+        graph.edges[edge.RightTable] = append(graph.edges[edge.RightTable], edge)
+    }
+
+    // 配置文件、dbt 和历史查询中的关联优先于命名约定推断
+    declared, err := loadDeclaredJoins()
+    if err != nil {
+        log.Printf("Error loading declared relationships: %s", err.Error())
+    }
+    for _, edge := range declared {
+        add(edge)
+    }
+    for _, edge := range dbtJoins() {
+        add(edge)
+    }
+    for _, edge := range learnedJoinEdges() {
+        add(edge)
+    }
+    for _, edge := range inferNamingJoins(catalog) {
+        add(edge)
+    }
+
+    joinGraphMu.Lock()
+    joinGraph = graph
+    joinGraphMu.Unlock()
+    log.Printf("Updated join graph for %s: %d relationships", catalog.Datasource, len(seen))
+}
+
+// CurrentJoinGraph 获取当前的关联关系图
+func CurrentJoinGraph() *JoinGraph {
+    joinGraphMu.RLock()
+    defer joinGraphMu.RUnlock()
+    return joinGraph
+}
+
+// IsJoinColumn 字段是否出现在某条关联条件中
+func (g *JoinGraph) IsJoinColumn(table, column string) bool {
+    for _, edge := range g.edges[table] {
+        if (edge.LeftTable == table && edge.LeftColumn == column) || (edge.RightTable == table && edge.RightColumn == column) {
+            return true
+        }
+    }
+    return false
+}
+
+// ConnectTables 在选中的表之间按最短关联路径补充桥接表，返回补充后的表和表之间允许的关联条件
+func (g *JoinGraph) ConnectTables(tables []string) ([]string, []JoinEdge) {
+    result := append([]string(nil), tables...)
+    included := make(map[string]bool)
+    for _, table := range tables {
+        included[table] = true
+    }
+
+    for i := 0; i < len(tables); i++ {
+        for j := i + 1; j < len(tables); j++ {
+            for _, table := range g.shortestPath(tables[i], tables[j]) {
+                if !included[table] {
+                    included[table] = true
+                    result = append(result, table)
+                }
+            }
+        }
+    }
+
+    var edges []JoinEdge
+    seen := make(map[string]bool)
+    for _, table := range result {
+        for _, edge := range g.edges[table] {
+            if included[edge.other(table)] && !seen[edge.key()] {
+                seen[edge.key()] = true
+                edges = append(edges, edge)
+            }
+        }
+    }
+    sort.Slice(edges, func(i, j int) bool {
+        return edges[i].key() < edges[j].key()
+    })
+    return result, edges
+}
+
+// shortestPath 广度优先搜索两张表之间的最短关联路径，返回路径上的所有表
+func (g *JoinGraph) shortestPath(from, to string) []string {
+    if from == to {
+        return nil
+    }
+    prev := map[string]string{from: ""}
+    queue := []string{from}
+    for depth := 0; len(queue) > 0 && depth < maxJoinPathLength; depth++ {
+        var next []string
+        for _, table := range queue {
+            for _, edge := range g.edges[table] {
+                neighbor := edge.other(table)
+                if _, ok := prev[neighbor]; ok {
+                    continue
+                }
+                prev[neighbor] = table
+                if neighbor == to {
+                    var path []string
+                    for t := to; t != ""; t = prev[t] {
+                        path = append([]string{t}, path...)
+                    }
+                    return path
+                }
+                next = append(next, neighbor)
+            }
+        }
+        queue = next
+    }
+    return nil
+}
+
+// FormatJoinConditions 生成提示词中允许使用的关联条件
+func FormatJoinConditions(edges []JoinEdge) string {
+    if len(edges) == 0 {
+        return ""
+    }
+    var b strings.Builder
+    b.WriteString("允许的关联条件：\n")
+    for _, edge := range edges {
+        b.WriteString("- " + edge.Condition() + "\n")
+    }
+    return b.String()
+}
+
+// inferNamingJoins 按命名约定推断关联：a.user_id 关联 dim_user.id 或 user.user_id
+func inferNamingJoins(catalog *SchemaCatalog) []JoinEdge {
+    type target struct {
+        table  string
+        column string
+    }
+    targets := make(map[string][]target)
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+        entity := entityName(table.Name)
+        for _, col := range table.Columns {
+            if col.Name == "id" || (col.Name == entity+"_id" && containsString(tableKeyList(table), col.Name)) {
+                targets[entity] = append(targets[entity], target{table.Name, col.Name})
+            }
+        }
+    }
+
+    var edges []JoinEdge
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+        for _, col := range table.Columns {
+            if !strings.HasSuffix(col.Name, "_id") {
+                continue
+            }
+            for _, t := range targets[strings.TrimSuffix(col.Name, "_id")] {
+                if t.table == table.Name {
+                    continue
+                }
+                edges = append(edges, JoinEdge{
+                    LeftTable:   table.Name,
+                    LeftColumn:  col.Name,
+                    RightTable:  t.table,
+                    RightColumn: t.column,
+                    Source:      JoinSourceNaming,
+                })
+            }
+        }
+    }
+    return edges
+}
+
+// 数仓分层前缀和常见后缀，去掉后得到表对应的实体名
+var (
+    tableLayerPrefixes = []string{"dim_", "dwd_", "dws_", "dwa_", "ods_", "ads_", "t_"}
+    tableEntitySuffixes = []string{"_info", "_dim", "_table", "s"}
+)
+
+func entityName(tableName string) string {
+    name := strings.ToLower(tableName)
+    for _, prefix := range tableLayerPrefixes {
+        if strings.HasPrefix(name, prefix) {
+            name = strings.TrimPrefix(name, prefix)
+            break
+        }
+    }
+    for _, suffix := range tableEntitySuffixes {
+        if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+            name = strings.TrimSuffix(name, suffix)
+            break
+        }
+    }
+    return name
+}
+
+func tableKeyList(table *TableSchema) []string {
+    if table.Details == nil {
+        return nil
+    }
+    return table.Details.KeyColumns
+}
+
+func catalogHasColumn(catalog *SchemaCatalog, tableName, column string) bool {
+    table, ok := catalog.Table(tableName)
+    if !ok {
+        return false
+    }
+    for _, col := range table.Columns {
+        if col.Name == column {
+            return true
+        }
+    }
+    return false
+}
+
+// loadDeclaredJoins 读取配置文件中声明的关联关系，文件不存在时忽略
+func loadDeclaredJoins() ([]JoinEdge, error) {
+    data, err := os.ReadFile(config.AppConfig.RelationshipsFile)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+
+    var declared declaredRelationships
+    if err := yaml.Unmarshal(data, &declared); err != nil {
+        return nil, fmt.Errorf("failed to parse %s: %v", config.AppConfig.RelationshipsFile, err)
+    }
+
+    var edges []JoinEdge
+    for _, rel := range declared.Relationships {
+        leftTable, leftColumn, ok1 := strings.Cut(rel.From, ".")
+        rightTable, rightColumn, ok2 := strings.Cut(rel.To, ".")
+        if !ok1 || !ok2 {
+            log.Printf("Invalid relationship %s -> %s, expected table.column", rel.From, rel.To)
+            continue
+        }
+        edges = append(edges, JoinEdge{
+            LeftTable:   leftTable,
+            LeftColumn:  leftColumn,
+            RightTable:  rightTable,
+            RightColumn: rightColumn,
+            Source:      JoinSourceDeclared,
+        })
+    }
+    return edges, nil
+}
+
+var (
+    sqlTableRefPattern = regexp.MustCompile("(?i)\\b(?:from|join)\\s+`?(\\w+)`?(?:\\.`?(\\w+)`?)?")
+    sqlAliasPattern    = regexp.MustCompile("^\\s+(?:(?i)as\\s+)?`?(\\w+)`?")
+    sqlJoinCondPattern = regexp.MustCompile("`?(\\w+)`?\\.`?(\\w+)`?\\s*=\\s*`?(\\w+)`?\\.`?(\\w+)`?")
+    sqlKeywords        = map[string]bool{
+        "on": true, "where": true, "left": true, "right": true, "inner": true, "outer": true, "full": true,
+        "cross": true, "join": true, "group": true, "order": true, "limit": true, "union": true, "using": true,
+        "having": true, "lateral": true,
+    }
+)
+
+// LearnJoins 从执行成功的SQL中提取关联条件，记录到历史关联中
+func LearnJoins(sql string) {
+    edges := extractJoinEdges(sql)
+    if len(edges) == 0 {
+        return
+    }
+
+    catalog, err := GetCatalog(DefaultDatasource)
+    if err != nil || !catalog.Loaded() {
+        return
+    }
+
+    // 写文件时仍持有锁，避免并发执行时较旧的快照后写入而覆盖新学到的关联
+    learnedJoinsMu.Lock()
+    learned := loadLearnedJoinsLocked()
+    var added []string
+    for _, edge := range edges {
+        if _, ok := learned[edge.key()]; ok {
+            continue
+        }
+        if !catalogHasColumn(catalog, edge.LeftTable, edge.LeftColumn) || !catalogHasColumn(catalog, edge.RightTable, edge.RightColumn) {
+            continue
+        }
+        learned[edge.key()] = edge
+        added = append(added, edge.key())
+    }
+    if len(added) == 0 {
+        learnedJoinsMu.Unlock()
+        return
+    }
+    err = saveLearnedJoinsLocked(learned)
+    if err != nil {
+        // 保存失败时撤销本次新增，保持内存与磁盘一致
+        for _, key := range added {
+            delete(learned, key)
+        }
+    }
+    learnedJoinsMu.Unlock()
+    if err != nil {
+        log.Printf("Error saving learned joins: %s", err.Error())
+        return
+    }
+
+    log.Printf("Learned %d join conditions from executed SQL", len(added))
+    UpdateJoinGraph(catalog)
+}
+
+// saveLearnedJoinsLocked 持久化历史关联，调用方需持有 learnedJoinsMu
+func saveLearnedJoinsLocked(learned map[string]JoinEdge) error {
+    data, err := json.MarshalIndent(learned, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(learnedJoinsPath(), data)
+}
+
+// ExtractSQLTables 提取SQL中 FROM 和 JOIN 之后引用的表名，按出现顺序去重
+func ExtractSQLTables(sql string) []string {
+    var tables []string
+    for _, loc := range sqlTableRefs(sql) {
+        table := sql[loc[2]:loc[3]]
+        // db.table 的写法取表名
+        if loc[4] >= 0 {
+            table = sql[loc[4]:loc[5]]
+        }
+        tables = appendUnique(tables, table)
+    }
+    return tables
+}
+
+// sqlTableRefs 匹配 FROM / JOIN 之后的表名，跳过字符串、注释以及函数参数中的 FROM，
+// 如 EXTRACT(YEAR FROM dt)、TRIM(BOTH ' ' FROM name)。括号内以 SELECT / WITH 开头的视为子查询
+func sqlTableRefs(sql string) [][]int {
+    matches := sqlTableRefPattern.FindAllStringSubmatchIndex(sql, -1)
+    if len(matches) == 0 {
+        return nil
+    }
+
+    skip := make([]bool, len(sql))
+    var calls []bool
+    var quote byte
+    for i := 0; i < len(sql); i++ {
+        c := sql[i]
+        switch {
+        case quote != 0:
+            if c == '\\' && i+1 < len(sql) {
+                skip[i] = true
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+            skip[i] = true
+            continue
+        case c == '\'' || c == '"':
+            quote = c
+            skip[i] = true
+            continue
+        case strings.HasPrefix(sql[i:], "--"):
+            for ; i < len(sql) && sql[i] != '\n'; i++ {
+                skip[i] = true
+            }
+            continue
+        case strings.HasPrefix(sql[i:], "/*"):
+            end := strings.Index(sql[i+2:], "*/")
+            if end < 0 {
+                end = len(sql) - i - 4
+            }
+            for j := i; j < i+end+4 && j < len(sql); j++ {
+                skip[j] = true
+            }
+            i += end + 3
+            continue
+        case c == '(':
+            rest := strings.ToLower(strings.TrimLeft(sql[i+1:], " \t\r\n("))
+            calls = append(calls, !strings.HasPrefix(rest, "select") && !strings.HasPrefix(rest, "with"))
+        case c == ')' && len(calls) > 0:
+            calls = calls[:len(calls)-1]
+        }
+        skip[i] = len(calls) > 0 && calls[len(calls)-1]
+    }
+
+    var refs [][]int
+    for _, loc := range matches {
+        if !skip[loc[0]] {
+            refs = append(refs, loc)
+        }
+    }
+    return refs
+}
+
+// extractJoinEdges 解析SQL中的表别名和 a.x = b.y 形式的关联条件
+func extractJoinEdges(sql string) []JoinEdge {
+    aliases := make(map[string]string)
+    for _, loc := range sqlTableRefs(sql) {
+        table := sql[loc[2]:loc[3]]
+        // db.table 的写法取表名
+        if loc[4] >= 0 {
+            table = sql[loc[4]:loc[5]]
+        }
+        aliases[table] = table
+        // 别名单独匹配，避免吞掉后面的 JOIN 关键字
+        if m := sqlAliasPattern.FindStringSubmatch(sql[loc[1]:]); m != nil && !sqlKeywords[strings.ToLower(m[1])] {
+            aliases[m[1]] = table
+        }
+    }
+
+    var edges []JoinEdge
+    for _, m := range sqlJoinCondPattern.FindAllStringSubmatch(sql, -1) {
+        left, ok1 := aliases[m[1]]
+        right, ok2 := aliases[m[3]]
+        if !ok1 || !ok2 || left == right {
+            continue
+        }
+        edges = append(edges, JoinEdge{
+            LeftTable:   left,
+            LeftColumn:  m[2],
+            RightTable:  right,
+            RightColumn: m[4],
+            Source:      JoinSourceHistory,
+        })
+    }
+    return edges
+}
+
+// learnedJoinEdges 获取历史查询中出现过的关联条件
+func learnedJoinEdges() []JoinEdge {
+    learnedJoinsMu.Lock()
+    defer learnedJoinsMu.Unlock()
+
+    learned := loadLearnedJoinsLocked()
+    edges := make([]JoinEdge, 0, len(learned))
+    for _, edge := range learned {
+        edges = append(edges, edge)
+    }
+    sort.Slice(edges, func(i, j int) bool {
+        return edges[i].key() < edges[j].key()
+    })
+    return edges
+}
+
+// loadLearnedJoinsLocked 首次调用时从磁盘加载历史关联，调用方需持有 learnedJoinsMu
+func loadLearnedJoinsLocked() map[string]JoinEdge {
+    if learnedJoins != nil {
+        return learnedJoins
+    }
+    learnedJoins = make(map[string]JoinEdge)
+    data, err := os.ReadFile(learnedJoinsPath())
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("Error loading learned joins: %s", err.Error())
+        }
+        return learnedJoins
+    }
+    if err := json.Unmarshal(data, &learnedJoins); err != nil {
+        log.Printf("Error decoding learned joins: %s", err.Error())
+        learnedJoins = make(map[string]JoinEdge)
+    }
+    return learnedJoins
+}
+
+func learnedJoinsPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "joins", "learned.json")
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestExtractSQLTables(t *testing.T) {
+    tests := []struct {
+        name string
+        sql  string
+        want []string
+    }{
+        {"single table", "SELECT * FROM orders", []string{"orders"}},
+        {"join with aliases", "SELECT * FROM orders o JOIN users u ON o.user_id = u.id", []string{"orders", "users"}},
+        {"database prefix", "SELECT * FROM `dw`.`orders` LEFT JOIN dw.users ON 1 = 1", []string{"orders", "users"}},
+        {"duplicates", "SELECT * FROM orders WHERE id IN (SELECT order_id FROM orders)", []string{"orders"}},
+        {"subquery", "SELECT * FROM (SELECT user_id FROM orders) t JOIN users u ON t.user_id = u.id", []string{"orders", "users"}},
+        {"in subquery", "SELECT * FROM users WHERE id IN ( select user_id from orders)", []string{"users", "orders"}},
+        {"cte", "WITH t AS (SELECT * FROM orders) SELECT * FROM t", []string{"orders", "t"}},
+        {
+            "from inside function calls",
+            "SELECT EXTRACT(YEAR FROM order_date), TRIM(BOTH ' ' FROM name) FROM orders o JOIN users u ON o.user_id = u.id",
+            []string{"orders", "users"},
+        },
+        {"nested function", "SELECT SUBSTRING(TRIM(LEADING '0' FROM code) FROM 2) FROM products", []string{"products"}},
+        {"string literal", "SELECT * FROM orders WHERE note = 'shipped from warehouse'", []string{"orders"}},
+        {"parenthesis in literal", "SELECT * FROM orders WHERE note = '(from x' AND id IN (SELECT id FROM users)", []string{"orders", "users"}},
+        {"comments", "SELECT * -- from audit\nFROM orders /* join logs */", []string{"orders"}},
+        {"no tables", "SELECT 1", nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := ExtractSQLTables(tt.sql); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ExtractSQLTables(%q) = %v, want %v", tt.sql, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestExtractJoinEdgesIgnoresFunctionFrom(t *testing.T) {
+    sql := "SELECT EXTRACT(YEAR FROM o.order_date) FROM orders o JOIN users u ON o.user_id = u.id"
+    edges := extractJoinEdges(sql)
+    if len(edges) != 1 {
+        t.Fatalf("extractJoinEdges(%q) returned %d edges, want 1", sql, len(edges))
+    }
+    if got := edges[0].Condition(); got != "orders.user_id = users.id" {
+        t.Errorf("join condition = %q, want %q", got, "orders.user_id = users.id")
+    }
+}
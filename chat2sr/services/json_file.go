@@ -0,0 +1,31 @@
+package services
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+)
+
+// loadJSONFile 读取本地JSON文件，文件不存在时保持 v 不变
+func loadJSONFile(path string, v interface{}) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil
+        }
+        return err
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        return fmt.Errorf("failed to decode %s: %v", path, err)
+    }
+    return nil
+}
+
+// saveJSONFile 以JSON格式原子写入本地文件
+func saveJSONFile(path string, v interface{}) error {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return fmt.Errorf("failed to encode %s: %v", path, err)
+    }
+    return writeFileAtomic(path, data)
+}
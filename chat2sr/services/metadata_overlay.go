@@ -0,0 +1,528 @@
+package services
+
+import (
+    "bytes"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+    "gopkg.in/yaml.v3"
+)
+
+var ErrMetadataNotFound = errors.New("metadata not found")
+
+// MetadataEntry 表或字段的补充元数据，不修改生产库中的注释
+type MetadataEntry struct {
+    Description string    `json:"description,omitempty" yaml:"description"`
+    Aliases     []string  `json:"aliases,omitempty" yaml:"aliases"`
+    Tags        []string  `json:"tags,omitempty" yaml:"tags"`
+    Deprecated  bool      `json:"deprecated,omitempty" yaml:"deprecated"`
+    DoNotUse    string    `json:"do_not_use,omitempty" yaml:"do_not_use"`
+    UpdatedAt   time.Time `json:"updated_at" yaml:"-"`
+    // 上次 dbt 导入写入的描述和标签，再次导入时先撤销再写入
+    DbtDescription string   `json:"dbt_description,omitempty" yaml:"-"`
+    DbtTags        []string `json:"dbt_tags,omitempty" yaml:"-"`
+}
+
+// TableMetadata 表的补充元数据及其字段的补充元数据
+type TableMetadata struct {
+    MetadataEntry `yaml:",inline"`
+    Columns       map[string]*MetadataEntry `json:"columns,omitempty" yaml:"columns"`
+}
+
+type metadataOverlay struct {
+    mu     sync.RWMutex
+    loaded bool
+    tables map[string]*TableMetadata
+}
+
+var overlay = &metadataOverlay{}
+
+func metadataOverlayPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "metadata_overlay.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载补充元数据。加载失败时不标记为已加载，下次访问重试，
+// update 据此拒绝写入，避免用空数据覆盖磁盘上手工维护的描述
+func (o *metadataOverlay) ensureLoaded() error {
+    o.mu.RLock()
+    loaded := o.loaded
+    o.mu.RUnlock()
+    if loaded {
+        return nil
+    }
+
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    if o.loaded {
+        return nil
+    }
+    tables := make(map[string]*TableMetadata)
+    if err := loadJSONFile(metadataOverlayPath(), &tables); err != nil {
+        log.Printf("Error loading metadata overlay: %s", err.Error())
+        return err
+    }
+    o.tables = tables
+    o.loaded = true
+    return nil
+}
+
+// update 在写锁内修改补充元数据并持久化，成功后更新表结构缓存中的注释使修改生效
+func (o *metadataOverlay) update(fn func(tables map[string]*TableMetadata) error) error {
+    if err := o.ensureLoaded(); err != nil {
+        return err
+    }
+
+    o.mu.Lock()
+    if err := fn(o.tables); err != nil {
+        o.mu.Unlock()
+        return err
+    }
+    err := saveJSONFile(metadataOverlayPath(), o.tables)
+    o.mu.Unlock()
+    if err != nil {
+        return err
+    }
+
+    if catalog, err := GetCatalog(DefaultDatasource); err == nil {
+        catalog.ApplyMetadata()
+    }
+    return nil
+}
+
+// ListMetadata 获取所有表的补充元数据
+func ListMetadata() map[string]TableMetadata {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    result := make(map[string]TableMetadata, len(overlay.tables))
+    for name, meta := range overlay.tables {
+        result[name] = copyTableMetadata(meta)
+    }
+    return result
+}
+
+// GetTableMetadata 获取单个表的补充元数据
+func GetTableMetadata(table string) (*TableMetadata, error) {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    meta, ok := overlay.tables[table]
+    if !ok {
+        return nil, ErrMetadataNotFound
+    }
+    copied := copyTableMetadata(meta)
+    return &copied, nil
+}
+
+// SetTableMetadata 设置表的补充元数据，保留已有的字段元数据
+func SetTableMetadata(table string, entry MetadataEntry) error {
+    return overlay.update(func(tables map[string]*TableMetadata) error {
+        meta := tableMetadataFor(tables, table)
+        entry.Aliases = trimStrings(entry.Aliases)
+        entry.Tags = trimStrings(entry.Tags)
+        entry.UpdatedAt = time.Now()
+        meta.MetadataEntry = entry
+        return nil
+    })
+}
+
+// SetColumnMetadata 设置字段的补充元数据
+func SetColumnMetadata(table, column string, entry MetadataEntry) error {
+    return overlay.update(func(tables map[string]*TableMetadata) error {
+        meta := tableMetadataFor(tables, table)
+        entry.Aliases = trimStrings(entry.Aliases)
+        entry.Tags = trimStrings(entry.Tags)
+        entry.UpdatedAt = time.Now()
+        meta.Columns[column] = &entry
+        return nil
+    })
+}
+
+// DeleteTableMetadata 删除表及其字段的补充元数据
+func DeleteTableMetadata(table string) error {
+    return overlay.update(func(tables map[string]*TableMetadata) error {
+        if _, ok := tables[table]; !ok {
+            return ErrMetadataNotFound
+        }
+        delete(tables, table)
+        return nil
+    })
+}
+
+// DeleteColumnMetadata 删除字段的补充元数据
+func DeleteColumnMetadata(table, column string) error {
+    return overlay.update(func(tables map[string]*TableMetadata) error {
+        meta, ok := tables[table]
+        if !ok {
+            return ErrMetadataNotFound
+        }
+        if _, ok := meta.Columns[column]; !ok {
+            return ErrMetadataNotFound
+        }
+        delete(meta.Columns, column)
+        return nil
+    })
+}
+
+// ImportMetadata 合并导入补充元数据，只覆盖导入内容中非空的属性，重复导入结果不变
+func ImportMetadata(imported map[string]*TableMetadata) (int, error) {
+    count := 0
+    err := overlay.update(func(tables map[string]*TableMetadata) error {
+        now := time.Now()
+        for table, in := range imported {
+            if in == nil {
+                continue
+            }
+            meta := tableMetadataFor(tables, table)
+            if mergeMetadataEntry(&meta.MetadataEntry, in.MetadataEntry) {
+                meta.UpdatedAt = now
+            }
+            count++
+            for column, colIn := range in.Columns {
+                if colIn == nil {
+                    continue
+                }
+                colMeta, ok := meta.Columns[column]
+                if !ok {
+                    colMeta = &MetadataEntry{}
+                    meta.Columns[column] = colMeta
+                }
+                if mergeMetadataEntry(colMeta, *colIn) {
+                    colMeta.UpdatedAt = now
+                }
+                count++
+            }
+        }
+        return nil
+    })
+    return count, err
+}
+
+// ReplaceDbtMetadata 用本次 dbt 导入的描述和标签替换上次导入的内容：dbt 中删除的描述和标签随之删除，
+// 手工维护的描述不被覆盖，手工添加的标签保留
+func ReplaceDbtMetadata(imported map[string]*TableMetadata) (int, error) {
+    count := 0
+    err := overlay.update(func(tables map[string]*TableMetadata) error {
+        now := time.Now()
+        names := make(map[string]bool, len(tables)+len(imported))
+        for table := range tables {
+            names[table] = true
+        }
+        for table, in := range imported {
+            if in != nil {
+                names[table] = true
+            }
+        }
+
+        for table := range names {
+            in := imported[table]
+            if in == nil {
+                in = &TableMetadata{}
+            } else {
+                count += 1 + len(in.Columns)
+            }
+            meta := tableMetadataFor(tables, table)
+            if replaceDbtEntry(&meta.MetadataEntry, in.MetadataEntry) {
+                meta.UpdatedAt = now
+            }
+            columns := make(map[string]bool, len(meta.Columns)+len(in.Columns))
+            for column := range meta.Columns {
+                columns[column] = true
+            }
+            for column, colIn := range in.Columns {
+                if colIn != nil {
+                    columns[column] = true
+                }
+            }
+            for column := range columns {
+                colMeta, ok := meta.Columns[column]
+                if !ok {
+                    colMeta = &MetadataEntry{}
+                    meta.Columns[column] = colMeta
+                }
+                colIn := in.Columns[column]
+                if colIn == nil {
+                    colIn = &MetadataEntry{}
+                }
+                if replaceDbtEntry(colMeta, *colIn) {
+                    colMeta.UpdatedAt = now
+                }
+                if metadataEntryEmpty(*colMeta) {
+                    delete(meta.Columns, column)
+                }
+            }
+            if metadataEntryEmpty(meta.MetadataEntry) && len(meta.Columns) == 0 {
+                delete(tables, table)
+            }
+        }
+        return nil
+    })
+    return count, err
+}
+
+// replaceDbtEntry 撤销上次 dbt 导入的描述和标签，再写入本次导入的内容，返回是否有变化
+func replaceDbtEntry(entry *MetadataEntry, in MetadataEntry) bool {
+    oldDescription, oldTags := entry.Description, strings.Join(entry.Tags, ",")
+
+    if entry.DbtDescription != "" && entry.Description == entry.DbtDescription {
+        entry.Description = ""
+    }
+    var tags []string
+    for _, tag := range entry.Tags {
+        if !containsString(entry.DbtTags, tag) {
+            tags = append(tags, tag)
+        }
+    }
+    entry.Tags = tags
+    entry.DbtDescription = ""
+    entry.DbtTags = nil
+
+    if description := strings.TrimSpace(in.Description); description != "" && entry.Description == "" {
+        entry.Description = description
+        entry.DbtDescription = description
+    }
+    for _, tag := range trimStrings(in.Tags) {
+        if !containsString(entry.Tags, tag) {
+            entry.Tags = append(entry.Tags, tag)
+            entry.DbtTags = append(entry.DbtTags, tag)
+        }
+    }
+    return entry.Description != oldDescription || strings.Join(entry.Tags, ",") != oldTags
+}
+
+// metadataEntryEmpty 条目中没有任何补充内容
+func metadataEntryEmpty(entry MetadataEntry) bool {
+    return entry.Description == "" && len(entry.Aliases) == 0 && len(entry.Tags) == 0 && !entry.Deprecated && entry.DoNotUse == ""
+}
+
+// ParseMetadataYAML 解析 YAML 格式的补充元数据：
+// tables: {表名: {description, aliases, tags, deprecated, do_not_use, columns: {字段名: {...}}}}
+func ParseMetadataYAML(data []byte) (map[string]*TableMetadata, error) {
+    var doc struct {
+        Tables map[string]*TableMetadata `yaml:"tables"`
+    }
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, fmt.Errorf("failed to parse metadata yaml: %v", err)
+    }
+    return doc.Tables, nil
+}
+
+// ParseMetadataCSV 解析 CSV 格式的补充元数据，表头为 table,column,description,aliases,tags,deprecated,do_not_use，
+// column 为空时表示表本身，aliases 和 tags 以 | 分隔
+func ParseMetadataCSV(data []byte) (map[string]*TableMetadata, error) {
+    reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))))
+    reader.FieldsPerRecord = -1
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read metadata csv header: %v", err)
+    }
+    index := make(map[string]int)
+    for i, name := range header {
+        index[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    if _, ok := index["table"]; !ok {
+        return nil, fmt.Errorf("metadata csv requires a table column")
+    }
+    field := func(record []string, name string) string {
+        if i, ok := index[name]; ok && i < len(record) {
+            return strings.TrimSpace(record[i])
+        }
+        return ""
+    }
+
+    tables := make(map[string]*TableMetadata)
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("failed to read metadata csv line %d: %v", line, err)
+        }
+        table := field(record, "table")
+        if table == "" {
+            continue
+        }
+        entry := MetadataEntry{
+            Description: field(record, "description"),
+            DoNotUse:    field(record, "do_not_use"),
+        }
+        if aliases := field(record, "aliases"); aliases != "" {
+            entry.Aliases = strings.Split(aliases, "|")
+        }
+        if tags := field(record, "tags"); tags != "" {
+            entry.Tags = strings.Split(tags, "|")
+        }
+        if deprecated := field(record, "deprecated"); deprecated != "" {
+            entry.Deprecated, err = strconv.ParseBool(deprecated)
+            if err != nil {
+                return nil, fmt.Errorf("invalid deprecated value on line %d: %s", line, deprecated)
+            }
+        }
+
+        meta := tableMetadataFor(tables, table)
+        if column := field(record, "column"); column != "" {
+            meta.Columns[column] = &entry
+        } else {
+            meta.MetadataEntry = entry
+        }
+    }
+    return tables, nil
+}
+
+// applyTableMetadata 将补充元数据合并到表注释中
+func applyTableMetadata(tables []TableInfo) []TableInfo {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    for i := range tables {
+        if meta, ok := overlay.tables[tables[i].Name]; ok {
+            tables[i].Comment = mergeComment(tables[i].Comment, meta.MetadataEntry)
+        }
+    }
+    return tables
+}
+
+// withMetadata 复制原始表结构并合并补充元数据，原始表结构不被修改
+func withMetadata(raw map[string]*TableSchema) map[string]*TableSchema {
+    tables := make(map[string]*TableSchema, len(raw))
+    for name, table := range raw {
+        merged := *table
+        merged.Comment = applyTableMetadata([]TableInfo{{Name: table.Name, Comment: table.Comment}})[0].Comment
+        merged.Columns = applyColumnMetadata(table.Name, append([]ColumnInfo(nil), table.Columns...))
+        tables[name] = &merged
+    }
+    return tables
+}
+
+// applyColumnMetadata 将补充元数据合并到字段注释中
+func applyColumnMetadata(table string, columns []ColumnInfo) []ColumnInfo {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    meta, ok := overlay.tables[table]
+    if !ok {
+        return columns
+    }
+    for i := range columns {
+        if entry, ok := meta.Columns[columns[i].Name]; ok {
+            columns[i].Comment = mergeComment(columns[i].Comment, *entry)
+        }
+    }
+    return columns
+}
+
+// IsTableDeprecated 表是否已标记为废弃，废弃的表不参与检索
+func IsTableDeprecated(table string) bool {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    meta, ok := overlay.tables[table]
+    return ok && meta.Deprecated
+}
+
+// IsColumnDeprecated 字段是否已标记为废弃，废弃的字段不写入提示词
+func IsColumnDeprecated(table, column string) bool {
+    overlay.ensureLoaded()
+    overlay.mu.RLock()
+    defer overlay.mu.RUnlock()
+
+    meta, ok := overlay.tables[table]
+    if !ok {
+        return false
+    }
+    entry, ok := meta.Columns[column]
+    return ok && entry.Deprecated
+}
+
+// excludeDeprecatedTables 过滤掉已废弃的表
+func excludeDeprecatedTables(tables []TableInfo) []TableInfo {
+    result := make([]TableInfo, 0, len(tables))
+    for _, table := range tables {
+        if !IsTableDeprecated(table.Name) {
+            result = append(result, table)
+        }
+    }
+    return result
+}
+
+// mergeComment 补充描述优先于库中的注释，别名、标签和使用提示追加在后面
+func mergeComment(comment string, entry MetadataEntry) string {
+    if entry.Description != "" {
+        comment = entry.Description
+    }
+    if len(entry.Aliases) > 0 {
+        comment = strings.TrimSpace(comment + " 别名: " + strings.Join(entry.Aliases, ", "))
+    }
+    if len(entry.Tags) > 0 {
+        comment = strings.TrimSpace(comment + " 标签: " + strings.Join(entry.Tags, ", "))
+    }
+    if entry.DoNotUse != "" {
+        comment = strings.TrimSpace(comment + " 注意: " + entry.DoNotUse)
+    }
+    return comment
+}
+
+// mergeMetadataEntry 导入时只覆盖非空属性，返回是否有变化
+func mergeMetadataEntry(dst *MetadataEntry, src MetadataEntry) bool {
+    changed := false
+    if src.Description != "" && src.Description != dst.Description {
+        dst.Description = src.Description
+        changed = true
+    }
+    for _, alias := range trimStrings(src.Aliases) {
+        if !containsString(dst.Aliases, alias) {
+            dst.Aliases = append(dst.Aliases, alias)
+            changed = true
+        }
+    }
+    for _, tag := range trimStrings(src.Tags) {
+        if !containsString(dst.Tags, tag) {
+            dst.Tags = append(dst.Tags, tag)
+            changed = true
+        }
+    }
+    if src.Deprecated && !dst.Deprecated {
+        dst.Deprecated = true
+        changed = true
+    }
+    if src.DoNotUse != "" && src.DoNotUse != dst.DoNotUse {
+        dst.DoNotUse = src.DoNotUse
+        changed = true
+    }
+    return changed
+}
+
+func tableMetadataFor(tables map[string]*TableMetadata, table string) *TableMetadata {
+    meta, ok := tables[table]
+    if !ok {
+        meta = &TableMetadata{}
+        tables[table] = meta
+    }
+    if meta.Columns == nil {
+        meta.Columns = make(map[string]*MetadataEntry)
+    }
+    return meta
+}
+
+func copyTableMetadata(meta *TableMetadata) TableMetadata {
+    copied := TableMetadata{MetadataEntry: meta.MetadataEntry, Columns: make(map[string]*MetadataEntry, len(meta.Columns))}
+    for name, entry := range meta.Columns {
+        entryCopy := *entry
+        copied.Columns[name] = &entryCopy
+    }
+    return copied
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "chat2sr/config"
+)
+
+func TestReplaceDbtEntry(t *testing.T) {
+    entry := MetadataEntry{Tags: []string{"manual"}}
+
+    replaceDbtEntry(&entry, MetadataEntry{Description: "订单明细", Tags: []string{"finance", "manual"}})
+    if entry.Description != "订单明细" || !reflect.DeepEqual(entry.Tags, []string{"manual", "finance"}) {
+        t.Fatalf("first import = %+v", entry)
+    }
+
+    // 重复导入结果不变
+    if replaceDbtEntry(&entry, MetadataEntry{Description: "订单明细", Tags: []string{"finance", "manual"}}) {
+        t.Errorf("repeated import reported a change: %+v", entry)
+    }
+
+    // dbt 中修改描述、删除标签后替换上次导入的内容，手工添加的标签保留
+    replaceDbtEntry(&entry, MetadataEntry{Description: "订单明细表"})
+    if entry.Description != "订单明细表" || !reflect.DeepEqual(entry.Tags, []string{"manual"}) {
+        t.Errorf("changed import = %+v", entry)
+    }
+
+    replaceDbtEntry(&entry, MetadataEntry{})
+    if entry.Description != "" || !reflect.DeepEqual(entry.Tags, []string{"manual"}) {
+        t.Errorf("removed import = %+v", entry)
+    }
+
+    // 手工维护的描述不被覆盖
+    manual := MetadataEntry{Description: "手工描述"}
+    replaceDbtEntry(&manual, MetadataEntry{Description: "dbt描述"})
+    if manual.Description != "手工描述" {
+        t.Errorf("manual description overwritten: %+v", manual)
+    }
+}
+
+func TestMetadataOverlayKeepsCorruptFile(t *testing.T) {
+    config.AppConfig.DataDir = t.TempDir()
+    overlay = &metadataOverlay{}
+    path := filepath.Join(config.AppConfig.DataDir, "metadata_overlay.json")
+    if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := SetTableMetadata("orders", MetadataEntry{Description: "订单"}); err == nil {
+        t.Fatal("SetTableMetadata succeeded after a failed load")
+    }
+    if data, _ := os.ReadFile(path); string(data) != "{" {
+        t.Errorf("metadata overlay overwritten: %q", data)
+    }
+}
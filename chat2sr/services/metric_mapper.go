@@ -0,0 +1,72 @@
+package services
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+)
+
+// MetricAnswer 用受治理指标回答问题的结果
+type MetricAnswer struct {
+    Query  MetricQuery `json:"metric_query"`
+    SQL    string      `json:"sql"`
+    Tables []string    `json:"tables"`
+}
+
+// AnswerWithMetrics 问题提到已定义的指标时，让模型把问题映射为指标查询，再确定性地编译为SQL。
+// 没有配置指标层、问题不涉及指标或无法映射时返回 nil，由调用方走自由生成SQL的流程
+func AnswerWithMetrics(question string) (*MetricAnswer, error) {
+    layer, err := CurrentSemanticLayer()
+    if err != nil {
+        if errors.Is(err, ErrSemanticLayerMissing) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    matched := layer.MatchMetrics(question)
+    if len(matched) == 0 {
+        return nil, nil
+    }
+
+    prompt := fmt.Sprintf(`将用户问题映射为指标查询。只能使用下面列出的指标和维度名称，今天是 %s。
+
+%s
+只返回一个JSON对象，不要包含任何解释，格式如下：
+{"metrics": ["指标名"], "dimensions": ["维度名"], "filters": [{"dimension": "维度名", "operator": "=", "values": ["取值"]}], "time_grain": "day|week|month|quarter|year 或空", "start_date": "YYYY-MM-DD 或空", "end_date": "YYYY-MM-DD（不含）或空", "limit": 0}
+如果问题无法只用这些指标和维度回答，返回 {}。
+
+用户问题: %s`, time.Now().Format("2006-01-02"), layer.FormatMetricCatalog(matched), question)
+
+    response, err := ProcessQuery(prompt)
+    if err != nil {
+        return nil, err
+    }
+
+    var query MetricQuery
+    if err := json.Unmarshal([]byte(extractJSONObject(response)), &query); err != nil {
+        return nil, fmt.Errorf("failed to parse metric query: %v", err)
+    }
+    if len(query.Metrics) == 0 {
+        log.Printf("Question cannot be answered with governed metrics: %s", question)
+        return nil, nil
+    }
+
+    sql, tables, err := layer.CompileMetricQuery(query)
+    if err != nil {
+        return nil, err
+    }
+    return &MetricAnswer{Query: query, SQL: sql, Tables: tables}, nil
+}
+
+// extractJSONObject 从模型回复中取出JSON对象，去掉可能的markdown代码块
+func extractJSONObject(response string) string {
+    start := strings.Index(response, "{")
+    end := strings.LastIndex(response, "}")
+    if start < 0 || end < start {
+        return "{}"
+    }
+    return response[start : end+1]
+}
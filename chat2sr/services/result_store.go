@@ -0,0 +1,339 @@
+package services
+
+import (
+    "compress/gzip"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "html"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+var (
+    ErrResultNotFound    = errors.New("result not found")
+    ErrResultSortInvalid = errors.New("unknown sort column")
+)
+
+// StoredResult 服务端保存的查询结果
+type StoredResult struct {
+    ID        string                   `json:"id"`
+    Query     string                   `json:"query"`
+    SQL       string                   `json:"sql"`
+    Columns   []string                 `json:"columns"`
+    Rows      []map[string]interface{} `json:"rows"`
+    CreatedAt time.Time                `json:"created_at"`
+}
+
+// ResultPage 查询结果分页
+type ResultPage struct {
+    ResultID  string                   `json:"result_id"`
+    HistoryID string                   `json:"history_id,omitempty"`
+    Columns   []string                 `json:"columns"`
+    Results   []map[string]interface{} `json:"results"`
+    Total     int                      `json:"total"`
+    Page      int                      `json:"page"`
+    PageSize  int                      `json:"page_size"`
+}
+
+// 最近读取的结果缓存在内存中，避免翻页时反复解压
+const resultCacheSize = 16
+
+var (
+    resultCacheMu sync.Mutex
+    resultCache   = make(map[string]*StoredResult)
+    resultIDRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// SaveResult 将查询结果压缩保存到本地磁盘，返回结果ID
+func SaveResult(query, sqlQuery string, columns []string, rows []map[string]interface{}) (string, error) {
+    result := &StoredResult{
+        ID:        newID(),
+        Query:     query,
+        SQL:       sqlQuery,
+        Columns:   columns,
+        Rows:      rows,
+        CreatedAt: time.Now(),
+    }
+
+    dir := resultDir()
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return "", fmt.Errorf("failed to create result directory: %v", err)
+    }
+
+    // 先写临时文件再重命名，避免读到写了一半的结果
+    tmp, err := os.CreateTemp(dir, result.ID+".*.tmp")
+    if err != nil {
+        return "", fmt.Errorf("failed to create result file: %v", err)
+    }
+    defer os.Remove(tmp.Name())
+
+    gz := gzip.NewWriter(tmp)
+    if err := json.NewEncoder(gz).Encode(result); err != nil {
+        tmp.Close()
+        return "", fmt.Errorf("failed to encode result: %v", err)
+    }
+    if err := gz.Close(); err != nil {
+        tmp.Close()
+        return "", fmt.Errorf("failed to compress result: %v", err)
+    }
+    if err := tmp.Close(); err != nil {
+        return "", fmt.Errorf("failed to write result file: %v", err)
+    }
+    if err := os.Rename(tmp.Name(), resultPath(result.ID)); err != nil {
+        return "", fmt.Errorf("failed to save result file: %v", err)
+    }
+
+    cacheResult(result)
+    return result.ID, nil
+}
+
+// LoadResult 读取保存的查询结果
+func LoadResult(id string) (*StoredResult, error) {
+    if !resultIDRegex.MatchString(id) {
+        return nil, ErrResultNotFound
+    }
+
+    resultCacheMu.Lock()
+    if result, ok := resultCache[id]; ok {
+        resultCacheMu.Unlock()
+        return result, nil
+    }
+    resultCacheMu.Unlock()
+
+    f, err := os.Open(resultPath(id))
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, ErrResultNotFound
+        }
+        return nil, fmt.Errorf("failed to open result file: %v", err)
+    }
+    defer f.Close()
+
+    gz, err := gzip.NewReader(f)
+    if err != nil {
+        return nil, fmt.Errorf("failed to decompress result: %v", err)
+    }
+    defer gz.Close()
+
+    // 使用 json.Number 保留大整数精度
+    decoder := json.NewDecoder(gz)
+    decoder.UseNumber()
+
+    var result StoredResult
+    if err := decoder.Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to decode result: %v", err)
+    }
+
+    cacheResult(&result)
+    return &result, nil
+}
+
+// GetResultPage 获取结果分页，sortBy 为空时保持原始顺序
+func GetResultPage(id string, page, pageSize int, sortBy string, desc bool) (ResultPage, error) {
+    result, err := LoadResult(id)
+    if err != nil {
+        return ResultPage{}, err
+    }
+
+    rows := result.Rows
+    if sortBy != "" {
+        if !containsString(result.Columns, sortBy) {
+            return ResultPage{}, fmt.Errorf("%w: %s", ErrResultSortInvalid, sortBy)
+        }
+        rows = make([]map[string]interface{}, len(result.Rows))
+        copy(rows, result.Rows)
+        sort.SliceStable(rows, func(i, j int) bool {
+            cmp := compareValues(rows[i][sortBy], rows[j][sortBy])
+            if desc {
+                return cmp > 0
+            }
+            return cmp < 0
+        })
+    }
+
+    total := len(rows)
+    start := (page - 1) * pageSize
+    if start > total {
+        start = total
+    }
+    end := start + pageSize
+    if end > total {
+        end = total
+    }
+
+    return ResultPage{
+        ResultID: id,
+        Columns:  result.Columns,
+        Results:  rows[start:end],
+        Total:    total,
+        Page:     page,
+        PageSize: pageSize,
+    }, nil
+}
+
+// WriteResultCSV 以CSV格式导出结果
+func WriteResultCSV(result *StoredResult, w io.Writer) error {
+    // 写入BOM，保证Excel打开中文不乱码
+    if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+        return err
+    }
+
+    writer := csv.NewWriter(w)
+    if err := writer.Write(result.Columns); err != nil {
+        return err
+    }
+    record := make([]string, len(result.Columns))
+    for _, row := range result.Rows {
+        for i, column := range result.Columns {
+            record[i] = formatValue(row[column])
+        }
+        if err := writer.Write(record); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+// WriteResultExcel 以Excel可识别的HTML表格格式导出结果
+func WriteResultExcel(result *StoredResult, w io.Writer) error {
+    var b strings.Builder
+    b.WriteString(`<html><head><meta charset="UTF-8"></head><body><table><tr>`)
+    for _, column := range result.Columns {
+        b.WriteString("<th>" + html.EscapeString(column) + "</th>")
+    }
+    b.WriteString("</tr>")
+    for _, row := range result.Rows {
+        b.WriteString("<tr>")
+        for _, column := range result.Columns {
+            b.WriteString("<td>" + html.EscapeString(formatValue(row[column])) + "</td>")
+        }
+        b.WriteString("</tr>")
+    }
+    b.WriteString("</table></body></html>")
+
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// StartResultCleanup 定期删除超过保留期的结果文件
+func StartResultCleanup() {
+    retention := config.AppConfig.ResultRetention
+    go func() {
+        ticker := time.NewTicker(time.Hour)
+        defer ticker.Stop()
+        for {
+            removeExpiredResults(retention)
+            <-ticker.C
+        }
+    }()
+}
+
+func removeExpiredResults(retention time.Duration) {
+    entries, err := os.ReadDir(resultDir())
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("Error reading result directory: %s", err.Error())
+        }
+        return
+    }
+
+    now := time.Now()
+    for _, entry := range entries {
+        info, err := entry.Info()
+        if err != nil || now.Sub(info.ModTime()) <= retention {
+            continue
+        }
+        if err := os.Remove(filepath.Join(resultDir(), entry.Name())); err != nil {
+            log.Printf("Error removing expired result %s: %s", entry.Name(), err.Error())
+            continue
+        }
+        id := strings.TrimSuffix(entry.Name(), ".json.gz")
+        resultCacheMu.Lock()
+        delete(resultCache, id)
+        resultCacheMu.Unlock()
+        log.Printf("Result %s expired and removed", id)
+    }
+}
+
+func cacheResult(result *StoredResult) {
+    resultCacheMu.Lock()
+    defer resultCacheMu.Unlock()
+
+    if len(resultCache) >= resultCacheSize {
+        // 淘汰最早创建的结果
+        oldestID := ""
+        for id, cached := range resultCache {
+            if oldestID == "" || cached.CreatedAt.Before(resultCache[oldestID].CreatedAt) {
+                oldestID = id
+            }
+        }
+        delete(resultCache, oldestID)
+    }
+    resultCache[result.ID] = result
+}
+
+func resultDir() string {
+    return filepath.Join(config.AppConfig.DataDir, "results")
+}
+
+func resultPath(id string) string {
+    return filepath.Join(resultDir(), id+".json.gz")
+}
+
+// compareValues 比较两个单元格的值，数字按数值比较，其余按字符串比较，nil 排在最前
+func compareValues(a, b interface{}) int {
+    if a == nil || b == nil {
+        switch {
+        case a == nil && b == nil:
+            return 0
+        case a == nil:
+            return -1
+        default:
+            return 1
+        }
+    }
+
+    sa, sb := formatValue(a), formatValue(b)
+    fa, errA := strconv.ParseFloat(sa, 64)
+    fb, errB := strconv.ParseFloat(sb, 64)
+    if errA == nil && errB == nil {
+        switch {
+        case fa < fb:
+            return -1
+        case fa > fb:
+            return 1
+        default:
+            return 0
+        }
+    }
+    return strings.Compare(sa, sb)
+}
+
+// formatValue 将单元格的值转换为字符串，nil 转为空字符串
+func formatValue(v interface{}) string {
+    if v == nil {
+        return ""
+    }
+    return fmt.Sprint(v)
+}
+
+func containsString(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+    "log"
+    "sort"
+    "chat2sr/config"
+)
+
+// 倒数排名融合的平滑常数
+const rrfK = 60
+
+// TableRetrieval 候选表检索结果，保留各路检索的得分便于排查选表问题
+type TableRetrieval struct {
+    Tables    []TableInfo  `json:"-"`
+    Method    string       `json:"method"`
+    BM25      []TableScore `json:"bm25,omitempty"`
+    Embedding []TableScore `json:"embedding,omitempty"`
+    Glossary  []string     `json:"glossary,omitempty"`
+}
+
+// RetrieveTables 结合 BM25 与向量索引检索相关表，两者都没有结果时退回关键词匹配
+func RetrieveTables(tables []TableInfo, question string) *TableRetrieval {
+    result := &TableRetrieval{}
+    // 已废弃的表不参与检索
+    tables = excludeDeprecatedTables(tables)
+
+    // 业务术语对应的表作为额外的一路检索结果
+    matched := MatchGlossary(question)
+    for _, entry := range matched {
+        result.Glossary = append(result.Glossary, entry.Term)
+    }
+    var glossaryScores []TableScore
+    for _, table := range GlossaryTables(matched) {
+        glossaryScores = append(glossaryScores, TableScore{Table: table, Score: 1})
+    }
+
+    bm25Index := GetBM25Index(DefaultDatasource)
+    if bm25Index.Size() > 0 {
+        result.BM25 = bm25Index.Search(question, config.AppConfig.BM25TopK, config.AppConfig.BM25MinScore)
+    }
+
+    embeddingIndex := GetTableIndex(DefaultDatasource)
+    if embeddingIndex.Size() > 0 {
+        scores, err := embeddingIndex.Search(question, config.AppConfig.RetrievalTopK, config.AppConfig.RetrievalMinScore)
+        if err != nil {
+            log.Printf("Error searching table index: %s", err.Error())
+        } else {
+            result.Embedding = scores
+        }
+    }
+
+    switch {
+    case len(result.BM25) > 0 && len(result.Embedding) > 0:
+        result.Method = "hybrid"
+    case len(result.BM25) > 0:
+        result.Method = "bm25"
+    case len(result.Embedding) > 0:
+        result.Method = "embedding"
+    default:
+        result.Method = "keyword"
+        result.Tables = FilterTablesByKeywords(tables, question)
+        return result
+    }
+    log.Printf("Retrieved tables by %s: bm25=%v embedding=%v", result.Method, result.BM25, result.Embedding)
+
+    byName := make(map[string]TableInfo, len(tables))
+    for _, table := range tables {
+        byName[table.Name] = table
+    }
+    for _, name := range fuseRankings(fusedTopK(), glossaryScores, result.BM25, result.Embedding) {
+        if table, ok := byName[name]; ok {
+            result.Tables = append(result.Tables, table)
+        }
+    }
+    return result
+}
+
+// fusedTopK 融合后保留的表数量，未配置时不少于任意一路检索返回的数量
+func fusedTopK() int {
+    if config.AppConfig.RetrievalFusedTopK > 0 {
+        return config.AppConfig.RetrievalFusedTopK
+    }
+    if config.AppConfig.BM25TopK > config.AppConfig.RetrievalTopK {
+        return config.AppConfig.BM25TopK
+    }
+    return config.AppConfig.RetrievalTopK
+}
+
+// fuseRankings 按倒数排名融合多路检索结果，不同检索方式的得分量纲不同，只使用名次
+func fuseRankings(topK int, rankings ...[]TableScore) []string {
+    fused := make(map[string]float64)
+    for _, ranking := range rankings {
+        for rank, score := range ranking {
+            fused[score.Table] += 1.0 / float64(rrfK+rank+1)
+        }
+    }
+
+    names := make([]string, 0, len(fused))
+    for name := range fused {
+        names = append(names, name)
+    }
+    sort.Slice(names, func(i, j int) bool {
+        if fused[names[i]] == fused[names[j]] {
+            return names[i] < names[j]
+        }
+        return fused[names[i]] > fused[names[j]]
+    })
+    if len(names) > topK {
+        names = names[:topK]
+    }
+    return names
+}
@@ -0,0 +1,559 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// 参数类型
+const (
+    ParamTypeString = "string"
+    ParamTypeNumber = "number"
+    ParamTypeDate   = "date"
+    ParamTypeEnum   = "enum"
+)
+
+// 枚举参数从字段取值时最多返回的选项数
+const maxEnumOptions = 1000
+
+var (
+    ErrSavedQueryNotFound = errors.New("saved query not found")
+    ErrSavedQueryInvalid  = errors.New("invalid saved query")
+    ErrQueryParamInvalid  = errors.New("invalid query parameter")
+    ErrVersionNotFound    = errors.New("saved query version not found")
+
+    // {{name}} 形式的参数占位符
+    queryParamPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+)
+
+// QueryParameter 保存的查询中的参数。enum 类型的可选值来自 Options，或者 Table.Column 的去重取值
+type QueryParameter struct {
+    Name     string   `json:"name"`
+    Type     string   `json:"type"`
+    Label    string   `json:"label,omitempty"`
+    Default  string   `json:"default,omitempty"`
+    Required bool     `json:"required,omitempty"`
+    Options  []string `json:"options,omitempty"`
+    Table    string   `json:"table,omitempty"`
+    Column   string   `json:"column,omitempty"`
+}
+
+// SavedQueryVersion 保存的查询的一个版本
+type SavedQueryVersion struct {
+    Version     int              `json:"version"`
+    Name        string           `json:"name"`
+    Description string           `json:"description,omitempty"`
+    SQL         string           `json:"sql"`
+    Parameters  []QueryParameter `json:"parameters"`
+    UpdatedBy   string           `json:"updated_by,omitempty"`
+    UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// SavedQuery 命名的参数化查询，修改时保留历史版本
+type SavedQuery struct {
+    ID        string              `json:"id"`
+    Owner     string              `json:"owner,omitempty"`
+    Tags      []string            `json:"tags,omitempty"`
+    CreatedAt time.Time           `json:"created_at"`
+    SavedQueryVersion
+    Revisions []SavedQueryVersion `json:"revisions,omitempty"`
+}
+
+type savedQueryStore struct {
+    mu      sync.Mutex
+    loaded  bool
+    queries map[string]*SavedQuery
+}
+
+var savedQueries = &savedQueryStore{}
+
+func savedQueriesPath() string {
+    return filepath.Join(config.AppConfig.DataDir, "saved_queries.json")
+}
+
+// ensureLoaded 首次访问时从磁盘加载保存的查询，调用方需持有锁。
+// 加载失败时不标记为已加载，下次访问重试；写操作需检查返回的错误，避免用空数据覆盖磁盘上的查询和历史版本
+func (s *savedQueryStore) ensureLoaded() error {
+    if s.loaded {
+        return nil
+    }
+    if s.queries == nil {
+        s.queries = make(map[string]*SavedQuery)
+    }
+    queries := make(map[string]*SavedQuery)
+    if err := loadJSONFile(savedQueriesPath(), &queries); err != nil {
+        log.Printf("Error loading saved queries: %s", err.Error())
+        return err
+    }
+    s.queries = queries
+    s.loaded = true
+    return nil
+}
+
+// saveLocked 持久化保存的查询，调用方需持有锁
+func (s *savedQueryStore) saveLocked() error {
+    return saveJSONFile(savedQueriesPath(), s.queries)
+}
+
+// ListSavedQueries 列出保存的查询，q 不为空时按名称、描述、标签和SQL检索，分词后每个词都需出现
+func ListSavedQueries(q string) []SavedQuery {
+    terms := Tokenize(q)
+
+    savedQueries.mu.Lock()
+    savedQueries.ensureLoaded()
+    var list []SavedQuery
+    for _, query := range savedQueries.queries {
+        text := strings.ToLower(strings.Join([]string{query.Name, query.Description, strings.Join(query.Tags, " "), query.SQL}, " "))
+        if len(terms) > 0 && !matchesAllTerms(text, terms) {
+            continue
+        }
+        copied := *query
+        copied.Revisions = nil
+        list = append(list, copied)
+    }
+    savedQueries.mu.Unlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].UpdatedAt.After(list[j].UpdatedAt)
+    })
+    return list
+}
+
+// GetSavedQuery 获取保存的查询，不包含历史版本
+func GetSavedQuery(id string) (*SavedQuery, error) {
+    savedQueries.mu.Lock()
+    defer savedQueries.mu.Unlock()
+    savedQueries.ensureLoaded()
+
+    query, ok := savedQueries.queries[id]
+    if !ok {
+        return nil, ErrSavedQueryNotFound
+    }
+    copied := *query
+    copied.Revisions = nil
+    return &copied, nil
+}
+
+// CreateSavedQuery 保存新的参数化查询
+func CreateSavedQuery(version SavedQueryVersion, owner string, tags []string) (*SavedQuery, error) {
+    if err := normalizeSavedQuery(&version); err != nil {
+        return nil, err
+    }
+
+    savedQueries.mu.Lock()
+    defer savedQueries.mu.Unlock()
+    if err := savedQueries.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    version.Version = 1
+    version.UpdatedBy = strings.TrimSpace(owner)
+    version.UpdatedAt = now
+    query := &SavedQuery{
+        ID:                newID(),
+        Owner:             strings.TrimSpace(owner),
+        Tags:              trimStrings(tags),
+        CreatedAt:         now,
+        SavedQueryVersion: version,
+    }
+    savedQueries.queries[query.ID] = query
+    if err := savedQueries.saveLocked(); err != nil {
+        delete(savedQueries.queries, query.ID)
+        return nil, err
+    }
+    copied := *query
+    return &copied, nil
+}
+
+// UpdateSavedQuery 修改保存的查询，当前版本移入历史版本，版本号加一
+func UpdateSavedQuery(id string, version SavedQueryVersion, editor string, tags []string) (*SavedQuery, error) {
+    if err := normalizeSavedQuery(&version); err != nil {
+        return nil, err
+    }
+
+    savedQueries.mu.Lock()
+    defer savedQueries.mu.Unlock()
+    if err := savedQueries.ensureLoaded(); err != nil {
+        return nil, err
+    }
+
+    previous, ok := savedQueries.queries[id]
+    if !ok {
+        return nil, ErrSavedQueryNotFound
+    }
+    updated := *previous
+    updated.Revisions = append(append([]SavedQueryVersion{}, previous.Revisions...), previous.SavedQueryVersion)
+    version.Version = previous.Version + 1
+    version.UpdatedBy = strings.TrimSpace(editor)
+    version.UpdatedAt = time.Now()
+    updated.SavedQueryVersion = version
+    if tags != nil {
+        updated.Tags = trimStrings(tags)
+    }
+
+    savedQueries.queries[id] = &updated
+    if err := savedQueries.saveLocked(); err != nil {
+        savedQueries.queries[id] = previous
+        return nil, err
+    }
+    copied := updated
+    copied.Revisions = nil
+    return &copied, nil
+}
+
+// DeleteSavedQuery 删除保存的查询及其历史版本
+func DeleteSavedQuery(id string) error {
+    savedQueries.mu.Lock()
+    defer savedQueries.mu.Unlock()
+    if err := savedQueries.ensureLoaded(); err != nil {
+        return err
+    }
+
+    previous, ok := savedQueries.queries[id]
+    if !ok {
+        return ErrSavedQueryNotFound
+    }
+    delete(savedQueries.queries, id)
+    if err := savedQueries.saveLocked(); err != nil {
+        savedQueries.queries[id] = previous
+        return err
+    }
+    return nil
+}
+
+// SavedQueryVersions 列出所有版本，最新版本在前
+func SavedQueryVersions(id string) ([]SavedQueryVersion, error) {
+    savedQueries.mu.Lock()
+    defer savedQueries.mu.Unlock()
+    savedQueries.ensureLoaded()
+
+    query, ok := savedQueries.queries[id]
+    if !ok {
+        return nil, ErrSavedQueryNotFound
+    }
+    versions := []SavedQueryVersion{query.SavedQueryVersion}
+    for i := len(query.Revisions) - 1; i >= 0; i-- {
+        versions = append(versions, query.Revisions[i])
+    }
+    return versions, nil
+}
+
+// DiffSavedQuery 比较两个版本的SQL和参数定义，返回统一格式的差异
+func DiffSavedQuery(id string, from, to int) (string, error) {
+    versions, err := SavedQueryVersions(id)
+    if err != nil {
+        return "", err
+    }
+    find := func(number int) *SavedQueryVersion {
+        for i := range versions {
+            if versions[i].Version == number {
+                return &versions[i]
+            }
+        }
+        return nil
+    }
+    before, after := find(from), find(to)
+    if before == nil || after == nil {
+        return "", ErrVersionNotFound
+    }
+    return UnifiedDiff(formatSavedQueryVersion(before), formatSavedQueryVersion(after),
+        fmt.Sprintf("version %d", from), fmt.Sprintf("version %d", to)), nil
+}
+
+// formatSavedQueryVersion 将版本内容展开为便于逐行比较的文本
+func formatSavedQueryVersion(version *SavedQueryVersion) string {
+    var b strings.Builder
+    b.WriteString("-- name: " + version.Name + "\n")
+    if version.Description != "" {
+        b.WriteString("-- description: " + version.Description + "\n")
+    }
+    for _, param := range version.Parameters {
+        line := fmt.Sprintf("-- param %s: %s", param.Name, param.Type)
+        if param.Default != "" {
+            line += " default=" + param.Default
+        }
+        if param.Required {
+            line += " required"
+        }
+        if len(param.Options) > 0 {
+            line += " options=" + strings.Join(param.Options, "|")
+        }
+        if param.Table != "" {
+            line += fmt.Sprintf(" from=%s.%s", param.Table, param.Column)
+        }
+        b.WriteString(line + "\n")
+    }
+    b.WriteString(version.SQL + "\n")
+    return b.String()
+}
+
+// normalizeSavedQuery 校验名称、SQL和参数定义，SQL需为只读查询，SQL中出现但未声明的参数按字符串类型补充
+func normalizeSavedQuery(version *SavedQueryVersion) error {
+    version.Name = strings.TrimSpace(version.Name)
+    version.Description = strings.TrimSpace(version.Description)
+    version.SQL = strings.TrimSpace(version.SQL)
+    if version.Name == "" || version.SQL == "" {
+        return fmt.Errorf("%w: name and SQL are required", ErrSavedQueryInvalid)
+    }
+
+    declared := make(map[string]bool)
+    for i := range version.Parameters {
+        param := &version.Parameters[i]
+        param.Name = strings.TrimSpace(param.Name)
+        if param.Type == "" {
+            param.Type = ParamTypeString
+        }
+        if declared[param.Name] {
+            return fmt.Errorf("%w: duplicate parameter %s", ErrSavedQueryInvalid, param.Name)
+        }
+        declared[param.Name] = true
+        switch param.Type {
+        case ParamTypeString, ParamTypeNumber, ParamTypeDate:
+        case ParamTypeEnum:
+            param.Options = trimStrings(param.Options)
+            if len(param.Options) == 0 && (param.Table == "" || param.Column == "") {
+                return fmt.Errorf("%w: enum parameter %s requires options or a table column", ErrSavedQueryInvalid, param.Name)
+            }
+        default:
+            return fmt.Errorf("%w: unknown type %s for parameter %s", ErrSavedQueryInvalid, param.Type, param.Name)
+        }
+        if param.Default != "" {
+            if _, err := convertParam(*param, param.Default, false); err != nil {
+                return fmt.Errorf("%w: invalid default for parameter %s", ErrSavedQueryInvalid, param.Name)
+            }
+        }
+    }
+
+    names, err := templateParams(version.SQL)
+    if err != nil {
+        return err
+    }
+    // 占位符替换为 NULL 后按只读查询校验，保存时就拒绝写操作和不存在的表
+    if err := ValidateSQL(queryParamPattern.ReplaceAllString(stripSQLComments(version.SQL), "NULL")); err != nil {
+        return fmt.Errorf("%w: %v", ErrSavedQueryInvalid, err)
+    }
+    for _, name := range names {
+        if !declared[name] {
+            version.Parameters = append(version.Parameters, QueryParameter{Name: name, Type: ParamTypeString, Required: true})
+            declared[name] = true
+        }
+    }
+    return nil
+}
+
+// templateParams 按出现顺序返回SQL中的参数名，注释中的占位符不算参数，参数不能写在引号内，否则无法绑定
+func templateParams(sqlText string) ([]string, error) {
+    sqlText = stripSQLComments(sqlText)
+    var names []string
+    for _, loc := range queryParamPattern.FindAllStringSubmatchIndex(sqlText, -1) {
+        if insideQuotes(sqlText, loc[0]) {
+            return nil, fmt.Errorf("%w: parameter %s must not be quoted, quoting is done when binding", ErrSavedQueryInvalid, sqlText[loc[2]:loc[3]])
+        }
+        names = append(names, sqlText[loc[2]:loc[3]])
+    }
+    return names, nil
+}
+
+// stripSQLComments 去掉引号以外的注释，避免注释中的占位符和问号被当作参数
+func stripSQLComments(text string) string {
+    var b strings.Builder
+    var quote byte
+    for i := 0; i < len(text); i++ {
+        ch := text[i]
+        switch {
+        case quote != 0:
+            b.WriteByte(ch)
+            if ch == '\\' && i+1 < len(text) {
+                i++
+                b.WriteByte(text[i])
+            } else if ch == quote {
+                quote = 0
+            }
+        case ch == '\'' || ch == '"' || ch == '`':
+            quote = ch
+            b.WriteByte(ch)
+        case ch == '#' || (ch == '-' && strings.HasPrefix(text[i:], "--")):
+            for i < len(text) && text[i] != '\n' {
+                i++
+            }
+            b.WriteByte('\n')
+        case ch == '/' && strings.HasPrefix(text[i:], "/*"):
+            end := strings.Index(text[i+2:], "*/")
+            if end < 0 {
+                i = len(text)
+            } else {
+                i += 2 + end + 1
+            }
+            b.WriteByte(' ')
+        default:
+            b.WriteByte(ch)
+        }
+    }
+    return b.String()
+}
+
+// insideQuotes 判断位置 pos 是否处于字符串字面量或反引号标识符中，text 中不能含有注释
+func insideQuotes(text string, pos int) bool {
+    var quote byte
+    for i := 0; i < pos; i++ {
+        ch := text[i]
+        switch {
+        case quote == 0 && (ch == '\'' || ch == '"' || ch == '`'):
+            quote = ch
+        case quote != 0 && ch == '\\':
+            i++
+        case ch == quote:
+            quote = 0
+        }
+    }
+    return quote != 0
+}
+
+// BindSavedQuery 去掉注释后将参数占位符替换为 ? 并按类型转换参数值，返回可直接执行的SQL和绑定参数
+func BindSavedQuery(query *SavedQuery, values map[string]string) (string, []interface{}, error) {
+    params := make(map[string]QueryParameter, len(query.Parameters))
+    for _, param := range query.Parameters {
+        params[param.Name] = param
+    }
+
+    var args []interface{}
+    var bindErr error
+    converted := make(map[string]interface{})
+    bound := queryParamPattern.ReplaceAllStringFunc(stripSQLComments(query.SQL), func(match string) string {
+        name := queryParamPattern.FindStringSubmatch(match)[1]
+        if bindErr != nil {
+            return match
+        }
+        value, ok := converted[name]
+        if !ok {
+            param, declared := params[name]
+            if !declared {
+                param = QueryParameter{Name: name, Type: ParamTypeString, Required: true}
+            }
+            raw, given := values[name]
+            if !given || strings.TrimSpace(raw) == "" {
+                raw = param.Default
+            }
+            if strings.TrimSpace(raw) == "" {
+                bindErr = fmt.Errorf("%w: missing value for %s", ErrQueryParamInvalid, name)
+                return match
+            }
+            value, bindErr = convertParam(param, raw, true)
+            if bindErr != nil {
+                return match
+            }
+            converted[name] = value
+        }
+        args = append(args, value)
+        return "?"
+    })
+    if bindErr != nil {
+        return "", nil, bindErr
+    }
+    return bound, args, nil
+}
+
+// convertParam 按参数类型校验并转换取值，checkEnum 为 true 时检查枚举值是否在可选值中
+func convertParam(param QueryParameter, raw string, checkEnum bool) (interface{}, error) {
+    raw = strings.TrimSpace(raw)
+    switch param.Type {
+    case ParamTypeNumber:
+        if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+            return n, nil
+        }
+        f, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            return nil, fmt.Errorf("%w: %s must be a number", ErrQueryParamInvalid, param.Name)
+        }
+        return f, nil
+    case ParamTypeDate:
+        t, err := time.Parse("2006-01-02", raw)
+        if err != nil {
+            return nil, fmt.Errorf("%w: %s must be a date in YYYY-MM-DD format", ErrQueryParamInvalid, param.Name)
+        }
+        return t.Format("2006-01-02"), nil
+    case ParamTypeEnum:
+        if !checkEnum {
+            return raw, nil
+        }
+        allowed, err := isEnumOption(param, raw)
+        if err != nil {
+            return nil, err
+        }
+        if !allowed {
+            return nil, fmt.Errorf("%w: %s is not an allowed value for %s", ErrQueryParamInvalid, raw, param.Name)
+        }
+        return raw, nil
+    default:
+        return raw, nil
+    }
+}
+
+// ParameterOptions 枚举参数的可选值：声明了 Options 时直接返回，否则查询字段的去重取值
+func ParameterOptions(param QueryParameter) ([]string, error) {
+    if len(param.Options) > 0 || param.Type != ParamTypeEnum {
+        return param.Options, nil
+    }
+    if err := checkEnumColumn(param); err != nil {
+        return nil, err
+    }
+
+    // 表名和字段名已在表结构中确认存在
+    query := fmt.Sprintf("SELECT DISTINCT `%s` AS v FROM `%s` WHERE `%s` IS NOT NULL ORDER BY v LIMIT %d",
+        param.Column, param.Table, param.Column, maxEnumOptions)
+    ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+    defer cancel()
+    _, rows, err := ExecuteSQLContext(ctx, query, nil)
+    if err != nil {
+        return nil, err
+    }
+    options := make([]string, 0, len(rows))
+    for _, row := range rows {
+        options = append(options, formatValue(row["v"]))
+    }
+    return options, nil
+}
+
+// isEnumOption 取值是否为枚举参数的可选值，可选值来自字段时直接查询该值是否存在，不受可选值列表长度的限制
+func isEnumOption(param QueryParameter, value string) (bool, error) {
+    if len(param.Options) > 0 {
+        return containsString(param.Options, value), nil
+    }
+    if err := checkEnumColumn(param); err != nil {
+        return false, err
+    }
+
+    query := fmt.Sprintf("SELECT 1 FROM `%s` WHERE `%s` = ? LIMIT 1", param.Table, param.Column)
+    ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+    defer cancel()
+    _, rows, err := ExecuteSQLContext(ctx, query, nil, value)
+    if err != nil {
+        return false, err
+    }
+    return len(rows) > 0, nil
+}
+
+// checkEnumColumn 确认枚举参数引用的表和字段存在
+func checkEnumColumn(param QueryParameter) error {
+    table, err := LookupTable(param.Table)
+    if err != nil {
+        return err
+    }
+    for _, col := range table.Columns {
+        if col.Name == param.Column {
+            return nil
+        }
+    }
+    return fmt.Errorf("%w: column %s not found in table %s", ErrQueryParamInvalid, param.Column, param.Table)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "chat2sr/config"
+)
+
+func TestTemplateParams(t *testing.T) {
+    tests := []struct {
+        name  string
+        sql   string
+        want  []string
+        valid bool
+    }{
+        {"plain", "SELECT * FROM t WHERE a = {{a}} AND b = {{ b }}", []string{"a", "b"}, true},
+        {"apostrophe in line comment", "SELECT * FROM t -- don't filter\nWHERE a = {{a}}", []string{"a"}, true},
+        {"placeholder in line comment", "SELECT * FROM t -- WHERE a = {{old}}\nWHERE b = {{b}}", []string{"b"}, true},
+        {"placeholder in hash comment", "SELECT * FROM t # {{old}}\nWHERE b = {{b}}", []string{"b"}, true},
+        {"placeholder in block comment", "SELECT * FROM t /* it's {{old}} */ WHERE b = {{b}}", []string{"b"}, true},
+        {"comment marker in literal", "SELECT * FROM t WHERE c = '--' AND b = {{b}}", []string{"b"}, true},
+        {"quoted placeholder", "SELECT * FROM t WHERE a = '{{a}}'", nil, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := templateParams(tt.sql)
+            if !tt.valid {
+                if err == nil {
+                    t.Errorf("templateParams(%q) = %v, want error", tt.sql, got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("templateParams(%q) error: %v", tt.sql, err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("templateParams(%q) = %v, want %v", tt.sql, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestBindSavedQuerySkipsComments(t *testing.T) {
+    query := &SavedQuery{SavedQueryVersion: SavedQueryVersion{
+        SQL: "SELECT * FROM t -- why? {{old}}\nWHERE a = {{a}} /* {{a}}? */ AND b = {{a}}",
+    }}
+    bound, args, err := BindSavedQuery(query, map[string]string{"a": "x"})
+    if err != nil {
+        t.Fatalf("BindSavedQuery error: %v", err)
+    }
+    if want := "SELECT * FROM t \nWHERE a = ?   AND b = ?"; bound != want {
+        t.Errorf("BindSavedQuery SQL = %q, want %q", bound, want)
+    }
+    if !reflect.DeepEqual(args, []interface{}{"x", "x"}) {
+        t.Errorf("BindSavedQuery args = %v, want [x x]", args)
+    }
+}
+
+func TestSavedQueriesKeepCorruptFile(t *testing.T) {
+    config.AppConfig.DataDir = t.TempDir()
+    savedQueries = &savedQueryStore{}
+    path := filepath.Join(config.AppConfig.DataDir, "saved_queries.json")
+    if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := DeleteSavedQuery("missing"); err == nil || errors.Is(err, ErrSavedQueryNotFound) {
+        t.Fatalf("DeleteSavedQuery after a failed load = %v, want load error", err)
+    }
+    if data, _ := os.ReadFile(path); string(data) != "{" {
+        t.Errorf("saved queries file overwritten: %q", data)
+    }
+}
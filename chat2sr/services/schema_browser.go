@@ -0,0 +1,107 @@
+package services
+
+import (
+    "sort"
+    "strings"
+    "time"
+    "chat2sr/config"
+)
+
+// DatasourceInfo 数据源概况
+type DatasourceInfo struct {
+    Name       string    `json:"name"`
+    Database   string    `json:"database"`
+    Host       string    `json:"host"`
+    TableCount int       `json:"table_count"`
+    Loaded     bool      `json:"loaded"`
+    LoadedAt   time.Time `json:"loaded_at"`
+}
+
+// SchemaSearchHit 表结构搜索结果
+type SchemaSearchHit struct {
+    Kind    string `json:"kind"`
+    Table   string `json:"table"`
+    Column  string `json:"column,omitempty"`
+    Type    string `json:"type,omitempty"`
+    Comment string `json:"comment"`
+    Score   int    `json:"score"`
+}
+
+// ListDatasources 列出已配置的数据源
+func ListDatasources() []DatasourceInfo {
+    catalogsMu.RLock()
+    defer catalogsMu.RUnlock()
+
+    var result []DatasourceInfo
+    for _, catalog := range catalogs {
+        result = append(result, DatasourceInfo{
+            Name:       catalog.Datasource,
+            Database:   config.AppConfig.DBName,
+            Host:       config.AppConfig.DBHost,
+            TableCount: len(catalog.Tables()),
+            Loaded:     catalog.Loaded(),
+            LoadedAt:   catalog.LoadedAt(),
+        })
+    }
+    sort.Slice(result, func(i, j int) bool {
+        return result[i].Name < result[j].Name
+    })
+    return result
+}
+
+// Search 在表名、字段名及其注释中搜索关键词，多个关键词需全部命中
+func (c *SchemaCatalog) Search(query string) []SchemaSearchHit {
+    terms := strings.Fields(strings.ToLower(query))
+    if len(terms) == 0 {
+        return nil
+    }
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    hits := []SchemaSearchHit{}
+    for _, name := range c.names {
+        table := c.tables[name]
+        if score := matchScore(terms, table.Name, table.Comment); score > 0 {
+            hits = append(hits, SchemaSearchHit{Kind: "table", Table: table.Name, Comment: table.Comment, Score: score})
+        }
+        for _, col := range table.Columns {
+            if score := matchScore(terms, col.Name, col.Comment); score > 0 {
+                hits = append(hits, SchemaSearchHit{
+                    Kind:    "column",
+                    Table:   table.Name,
+                    Column:  col.Name,
+                    Type:    col.Type,
+                    Comment: col.Comment,
+                    Score:   score,
+                })
+            }
+        }
+    }
+
+    sort.SliceStable(hits, func(i, j int) bool {
+        return hits[i].Score > hits[j].Score
+    })
+    return hits
+}
+
+// matchScore 计算关键词在名称和注释中的匹配得分，名称完全匹配最高，任一关键词未命中返回0
+func matchScore(terms []string, name, comment string) int {
+    name = strings.ToLower(name)
+    comment = strings.ToLower(comment)
+
+    score := 0
+    for _, term := range terms {
+        switch {
+        case name == term:
+            score += 3
+        case strings.Contains(name, term):
+            score += 2
+        case strings.Contains(comment, term):
+            score += 1
+        default:
+            return 0
+        }
+    }
+    return score
+}
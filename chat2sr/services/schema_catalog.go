@@ -0,0 +1,341 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// DefaultDatasource 当前配置的 StarRocks 数据源名称
+const DefaultDatasource = "default"
+
+var ErrDatasourceNotFound = errors.New("datasource not found")
+
+// ColumnInfo 字段信息
+type ColumnInfo struct {
+    Name    string `json:"name"`
+    Type    string `json:"type"`
+    Comment string `json:"comment"`
+}
+
+// TableSchema 表及其字段信息
+type TableSchema struct {
+    Name    string        `json:"name"`
+    Comment string        `json:"comment"`
+    Columns []ColumnInfo  `json:"columns"`
+    Details *TableDetails `json:"details,omitempty"`
+}
+
+// SchemaChanges 两次加载之间的结构变化
+type SchemaChanges struct {
+    AddedTables   []string            `json:"added_tables"`
+    DroppedTables []string            `json:"dropped_tables"`
+    AlteredTables map[string][]string `json:"altered_tables"`
+}
+
+// SchemaCatalog 单个数据源的表结构缓存
+type SchemaCatalog struct {
+    Datasource string
+
+    mu       sync.RWMutex
+    // raw 为数据库中的原始注释，tables 为合并补充元数据后的结果
+    raw      map[string]*TableSchema
+    tables   map[string]*TableSchema
+    names    []string
+    loadedAt time.Time
+
+    refreshMu sync.Mutex
+
+    rebuildMu    sync.Mutex
+    rebuildTimer *time.Timer
+}
+
+// 补充元数据修改后，等待这段时间内没有新的修改再重建索引
+const indexRebuildDelay = 5 * time.Second
+
+var (
+    catalogsMu sync.RWMutex
+    catalogs   = make(map[string]*SchemaCatalog)
+)
+
+// StartSchemaCatalog 后台加载表结构并按TTL定期刷新
+func StartSchemaCatalog() {
+    catalog := &SchemaCatalog{Datasource: DefaultDatasource}
+
+    catalogsMu.Lock()
+    catalogs[catalog.Datasource] = catalog
+    catalogsMu.Unlock()
+
+    ttl := config.AppConfig.SchemaCacheTTL
+    go func() {
+        if _, err := catalog.Refresh(); err != nil {
+            log.Printf("Error loading schema catalog for %s: %s", catalog.Datasource, err.Error())
+        }
+        if ttl <= 0 {
+            return
+        }
+        ticker := time.NewTicker(ttl)
+        defer ticker.Stop()
+        for range ticker.C {
+            if _, err := catalog.Refresh(); err != nil {
+                log.Printf("Error refreshing schema catalog for %s: %s", catalog.Datasource, err.Error())
+            }
+        }
+    }()
+}
+
+// GetCatalog 获取指定数据源的表结构缓存
+func GetCatalog(datasource string) (*SchemaCatalog, error) {
+    if datasource == "" {
+        datasource = DefaultDatasource
+    }
+
+    catalogsMu.RLock()
+    defer catalogsMu.RUnlock()
+
+    catalog, ok := catalogs[datasource]
+    if !ok {
+        return nil, ErrDatasourceNotFound
+    }
+    return catalog, nil
+}
+
+// Refresh 从 INFORMATION_SCHEMA 重新加载表结构，并记录与上次加载相比的变化
+func (c *SchemaCatalog) Refresh() (SchemaChanges, error) {
+    c.refreshMu.Lock()
+    defer c.refreshMu.Unlock()
+
+    start := time.Now()
+    tables, err := queryAllTables()
+    if err != nil {
+        return SchemaChanges{}, err
+    }
+    columns, err := queryAllColumns()
+    if err != nil {
+        return SchemaChanges{}, err
+    }
+
+    loaded := make(map[string]*TableSchema, len(tables))
+    names := make([]string, 0, len(tables))
+    for _, table := range tables {
+        loaded[table.Name] = &TableSchema{
+            Name:    table.Name,
+            Comment: table.Comment,
+            Columns: columns[table.Name],
+        }
+        names = append(names, table.Name)
+    }
+    sort.Strings(names)
+
+    // 结构没有变化的表沿用上次的建表信息，新增和变更的表重新获取
+    c.mu.RLock()
+    reuse := make(map[string]*TableDetails)
+    for name, previous := range c.raw {
+        if table, ok := loaded[name]; ok && previous.Details != nil && len(diffColumns(previous, table)) == 0 {
+            reuse[name] = previous.Details
+        }
+    }
+    c.mu.RUnlock()
+    details, err := GetTableDetails(names, reuse)
+    if err != nil {
+        log.Printf("Error loading table details for %s: %s", c.Datasource, err.Error())
+    }
+    for name, detail := range details {
+        loaded[name].Details = detail
+    }
+
+    c.mu.Lock()
+    previous := c.raw
+    c.raw = loaded
+    c.tables = withMetadata(loaded)
+    c.names = names
+    c.loadedAt = time.Now()
+    c.mu.Unlock()
+
+    // 首次加载不做变化检测
+    changes := SchemaChanges{AlteredTables: make(map[string][]string)}
+    if previous != nil {
+        changes = diffSchemas(previous, loaded)
+        logSchemaChanges(c.Datasource, changes)
+    }
+    log.Printf("Loaded schema catalog for %s: %d tables in %s", c.Datasource, len(loaded), time.Since(start))
+
+    c.onRefreshed()
+    return changes, nil
+}
+
+// onRefreshed 表结构加载后在后台更新依赖表结构的索引
+func (c *SchemaCatalog) onRefreshed() {
+    go UpdateTableIndex(c)
+    go UpdateBM25Index(c)
+    go UpdateJoinGraph(c)
+}
+
+// ApplyMetadata 补充元数据修改后，在缓存的原始表结构上重新合并注释，不重新查询数据库，
+// 依赖表结构的索引在连续修改结束后统一重建
+func (c *SchemaCatalog) ApplyMetadata() {
+    c.mu.Lock()
+    if c.raw == nil {
+        c.mu.Unlock()
+        return
+    }
+    c.tables = withMetadata(c.raw)
+    c.mu.Unlock()
+
+    c.rebuildMu.Lock()
+    defer c.rebuildMu.Unlock()
+    if c.rebuildTimer != nil {
+        c.rebuildTimer.Stop()
+    }
+    c.rebuildTimer = time.AfterFunc(indexRebuildDelay, c.onRefreshed)
+}
+
+// Loaded 表结构是否已加载
+func (c *SchemaCatalog) Loaded() bool {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    return c.tables != nil
+}
+
+// LoadedAt 最近一次加载时间
+func (c *SchemaCatalog) LoadedAt() time.Time {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    return c.loadedAt
+}
+
+// Tables 按表名排序返回所有表及注释
+func (c *SchemaCatalog) Tables() []TableInfo {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    tables := make([]TableInfo, 0, len(c.names))
+    for _, name := range c.names {
+        table := c.tables[name]
+        tables = append(tables, TableInfo{Name: table.Name, Comment: table.Comment})
+    }
+    return tables
+}
+
+// Table 获取单个表的结构
+func (c *SchemaCatalog) Table(name string) (*TableSchema, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    table, ok := c.tables[name]
+    return table, ok
+}
+
+// CatalogTables 从缓存获取所有表，缓存未加载时直接查询数据库
+func CatalogTables() ([]TableInfo, error) {
+    catalog, err := GetCatalog(DefaultDatasource)
+    if err != nil || !catalog.Loaded() {
+        return GetAllTablesWithComments()
+    }
+    return catalog.Tables(), nil
+}
+
+// CatalogTable 从缓存获取表结构，缓存未加载或表不存在时直接查询数据库（不含StarRocks表信息）
+func CatalogTable(tableName string) (*TableSchema, error) {
+    catalog, err := GetCatalog(DefaultDatasource)
+    if err == nil && catalog.Loaded() {
+        if table, ok := catalog.Table(tableName); ok {
+            return table, nil
+        }
+    }
+
+    columns, err := GetTableSchemaWithComments(tableName)
+    if err != nil {
+        return nil, err
+    }
+    table := &TableSchema{Name: tableName}
+    for _, col := range columns {
+        table.Columns = append(table.Columns, ColumnInfo{Name: col["name"], Type: col["type"], Comment: col["comment"]})
+    }
+    return table, nil
+}
+
+// CatalogTableSchema 从缓存获取表字段
+func CatalogTableSchema(tableName string) ([]ColumnInfo, error) {
+    table, err := CatalogTable(tableName)
+    if err != nil {
+        return nil, err
+    }
+    return table.Columns, nil
+}
+
+// diffSchemas 比较两次加载的表结构
+func diffSchemas(previous, current map[string]*TableSchema) SchemaChanges {
+    changes := SchemaChanges{AlteredTables: make(map[string][]string)}
+
+    for name := range current {
+        if _, ok := previous[name]; !ok {
+            changes.AddedTables = append(changes.AddedTables, name)
+        }
+    }
+    for name, old := range previous {
+        table, ok := current[name]
+        if !ok {
+            changes.DroppedTables = append(changes.DroppedTables, name)
+            continue
+        }
+        if diffs := diffColumns(old, table); len(diffs) > 0 {
+            changes.AlteredTables[name] = diffs
+        }
+    }
+
+    sort.Strings(changes.AddedTables)
+    sort.Strings(changes.DroppedTables)
+    return changes
+}
+
+func diffColumns(old, current *TableSchema) []string {
+    var diffs []string
+    if old.Comment != current.Comment {
+        diffs = append(diffs, fmt.Sprintf("table comment changed: %q -> %q", old.Comment, current.Comment))
+    }
+
+    oldColumns := make(map[string]ColumnInfo, len(old.Columns))
+    for _, col := range old.Columns {
+        oldColumns[col.Name] = col
+    }
+    for _, col := range current.Columns {
+        prev, ok := oldColumns[col.Name]
+        if !ok {
+            diffs = append(diffs, fmt.Sprintf("column added: %s %s", col.Name, col.Type))
+            continue
+        }
+        delete(oldColumns, col.Name)
+        if prev.Type != col.Type {
+            diffs = append(diffs, fmt.Sprintf("column %s type changed: %s -> %s", col.Name, prev.Type, col.Type))
+        }
+        if prev.Comment != col.Comment {
+            diffs = append(diffs, fmt.Sprintf("column %s comment changed: %q -> %q", col.Name, prev.Comment, col.Comment))
+        }
+    }
+    for _, col := range old.Columns {
+        if _, ok := oldColumns[col.Name]; ok {
+            diffs = append(diffs, fmt.Sprintf("column dropped: %s", col.Name))
+        }
+    }
+    return diffs
+}
+
+func logSchemaChanges(datasource string, changes SchemaChanges) {
+    if len(changes.AddedTables) > 0 {
+        log.Printf("Schema change in %s: tables added: %s", datasource, strings.Join(changes.AddedTables, ", "))
+    }
+    if len(changes.DroppedTables) > 0 {
+        log.Printf("Schema change in %s: tables dropped: %s", datasource, strings.Join(changes.DroppedTables, ", "))
+    }
+    for table, diffs := range changes.AlteredTables {
+        for _, diff := range diffs {
+            log.Printf("Schema change in %s: table %s %s", datasource, table, diff)
+        }
+    }
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+    "gopkg.in/yaml.v3"
+)
+
+var (
+    ErrSemanticLayerMissing = errors.New("semantic layer is not configured")
+    ErrInvalidMetricQuery   = errors.New("invalid metric query")
+)
+
+// MetricDefinition 受治理的指标定义
+type MetricDefinition struct {
+    Name        string   `yaml:"name" json:"name"`
+    Label       string   `yaml:"label" json:"label,omitempty"`
+    Synonyms    []string `yaml:"synonyms" json:"synonyms,omitempty"`
+    Description string   `yaml:"description" json:"description,omitempty"`
+    Expression  string   `yaml:"expression" json:"expression"`
+    Table       string   `yaml:"table" json:"table"`
+    TimeColumn  string   `yaml:"time_column" json:"time_column,omitempty"`
+    Filters     []string `yaml:"filters" json:"filters,omitempty"`
+    Dimensions  []string `yaml:"dimensions" json:"dimensions"`
+}
+
+// DimensionDefinition 维度定义，Table 为空时表示指标基础表上的字段
+type DimensionDefinition struct {
+    Name     string   `yaml:"name" json:"name"`
+    Label    string   `yaml:"label" json:"label,omitempty"`
+    Synonyms []string `yaml:"synonyms" json:"synonyms,omitempty"`
+    Table    string   `yaml:"table" json:"table,omitempty"`
+    Column   string   `yaml:"column" json:"column"`
+    Join     string   `yaml:"join" json:"join,omitempty"`
+}
+
+// SemanticLayer 指标层配置
+type SemanticLayer struct {
+    Metrics    []MetricDefinition    `yaml:"metrics" json:"metrics"`
+    Dimensions []DimensionDefinition `yaml:"dimensions" json:"dimensions"`
+
+    metrics    map[string]*MetricDefinition
+    dimensions map[string]*DimensionDefinition
+}
+
+// MetricFilter 指标查询的维度过滤条件
+type MetricFilter struct {
+    Dimension string   `json:"dimension"`
+    Operator  string   `json:"operator"`
+    Values    []string `json:"values"`
+}
+
+// MetricQuery 指标查询
+type MetricQuery struct {
+    Metrics    []string       `json:"metrics"`
+    Dimensions []string       `json:"dimensions"`
+    Filters    []MetricFilter `json:"filters"`
+    TimeGrain  string         `json:"time_grain"`
+    StartDate  string         `json:"start_date"`
+    EndDate    string         `json:"end_date"`
+    Limit      int            `json:"limit"`
+}
+
+var (
+    metricTimeGrains   = []string{"day", "week", "month", "quarter", "year"}
+    metricOperators    = []string{"=", "!=", "<>", ">", ">=", "<", "<=", "in", "not in", "like"}
+    metricDatePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+    metricIdentPattern = regexp.MustCompile(`^\w+$`)
+)
+
+var (
+    semanticLayerMu      sync.Mutex
+    semanticLayer        *SemanticLayer
+    semanticLayerModTime time.Time
+)
+
+// CurrentSemanticLayer 获取指标层配置，配置文件修改后自动重新加载
+func CurrentSemanticLayer() (*SemanticLayer, error) {
+    semanticLayerMu.Lock()
+    defer semanticLayerMu.Unlock()
+
+    path := config.AppConfig.SemanticLayerFile
+    info, err := os.Stat(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            semanticLayer = nil
+            return nil, ErrSemanticLayerMissing
+        }
+        return nil, err
+    }
+    if semanticLayer != nil && info.ModTime().Equal(semanticLayerModTime) {
+        return semanticLayer, nil
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    layer, err := parseSemanticLayer(data)
+    if err != nil {
+        return nil, fmt.Errorf("failed to load %s: %v", path, err)
+    }
+    semanticLayer = layer
+    semanticLayerModTime = info.ModTime()
+    log.Printf("Loaded semantic layer: %d metrics, %d dimensions", len(layer.Metrics), len(layer.Dimensions))
+    return semanticLayer, nil
+}
+
+func parseSemanticLayer(data []byte) (*SemanticLayer, error) {
+    var layer SemanticLayer
+    if err := yaml.Unmarshal(data, &layer); err != nil {
+        return nil, err
+    }
+
+    layer.dimensions = make(map[string]*DimensionDefinition)
+    for i := range layer.Dimensions {
+        dim := &layer.Dimensions[i]
+        if !metricIdentPattern.MatchString(dim.Name) || dim.Column == "" {
+            return nil, fmt.Errorf("dimension %q requires a name and a column", dim.Name)
+        }
+        if dim.Table != "" && dim.Join == "" {
+            return nil, fmt.Errorf("dimension %s on table %s requires a join condition", dim.Name, dim.Table)
+        }
+        if _, ok := layer.dimensions[dim.Name]; ok {
+            return nil, fmt.Errorf("duplicate dimension %s", dim.Name)
+        }
+        layer.dimensions[dim.Name] = dim
+    }
+
+    layer.metrics = make(map[string]*MetricDefinition)
+    for i := range layer.Metrics {
+        metric := &layer.Metrics[i]
+        if !metricIdentPattern.MatchString(metric.Name) || metric.Expression == "" || metric.Table == "" {
+            return nil, fmt.Errorf("metric %q requires a name, an expression and a table", metric.Name)
+        }
+        if _, ok := layer.metrics[metric.Name]; ok {
+            return nil, fmt.Errorf("duplicate metric %s", metric.Name)
+        }
+        for _, name := range metric.Dimensions {
+            if _, ok := layer.dimensions[name]; !ok {
+                return nil, fmt.Errorf("metric %s references unknown dimension %s", metric.Name, name)
+            }
+        }
+        layer.metrics[metric.Name] = metric
+    }
+    return &layer, nil
+}
+
+// Metric 按名称查找指标
+func (l *SemanticLayer) Metric(name string) (*MetricDefinition, bool) {
+    metric, ok := l.metrics[name]
+    return metric, ok
+}
+
+// Dimension 按名称查找维度
+func (l *SemanticLayer) Dimension(name string) (*DimensionDefinition, bool) {
+    dim, ok := l.dimensions[name]
+    return dim, ok
+}
+
+// MatchMetrics 找出问题中提到的指标（名称、中文名或同义词）
+func (l *SemanticLayer) MatchMetrics(question string) []MetricDefinition {
+    questionLower := strings.ToLower(question)
+    var matched []MetricDefinition
+    for _, metric := range l.Metrics {
+        names := append([]string{metric.Name, metric.Label}, metric.Synonyms...)
+        for _, name := range names {
+            if containsTerm(questionLower, strings.ToLower(name)) {
+                matched = append(matched, metric)
+                break
+            }
+        }
+    }
+    return matched
+}
+
+// CompileMetricQuery 将指标查询确定性地编译为 StarRocks SQL，同样的查询总是得到同样的SQL
+func (l *SemanticLayer) CompileMetricQuery(query MetricQuery) (string, []string, error) {
+    if len(query.Metrics) == 0 {
+        return "", nil, fmt.Errorf("%w: at least one metric is required", ErrInvalidMetricQuery)
+    }
+
+    var metrics []*MetricDefinition
+    for _, name := range query.Metrics {
+        metric, ok := l.metrics[name]
+        if !ok {
+            return "", nil, fmt.Errorf("%w: unknown metric %s", ErrInvalidMetricQuery, name)
+        }
+        metrics = append(metrics, metric)
+    }
+    base := metrics[0]
+    // 默认过滤条件作用于整个查询，只有基础表和默认过滤条件相同的指标才能放在一起计算
+    for _, metric := range metrics[1:] {
+        if metric.Table != base.Table || strings.Join(metric.Filters, "\n") != strings.Join(base.Filters, "\n") {
+            return "", nil, fmt.Errorf("%w: metrics %s and %s have different tables or default filters, query them separately", ErrInvalidMetricQuery, base.Name, metric.Name)
+        }
+    }
+
+    tables := []string{base.Table}
+    var selects, groupBy, joins, where []string
+    joined := make(map[string]bool)
+    useDimension := func(name string) (*DimensionDefinition, error) {
+        dim, ok := l.dimensions[name]
+        if !ok {
+            return nil, fmt.Errorf("%w: unknown dimension %s", ErrInvalidMetricQuery, name)
+        }
+        for _, metric := range metrics {
+            if !containsString(metric.Dimensions, name) {
+                return nil, fmt.Errorf("%w: dimension %s is not allowed for metric %s", ErrInvalidMetricQuery, name, metric.Name)
+            }
+        }
+        if dim.Table != "" && dim.Table != base.Table && !joined[dim.Table] {
+            joined[dim.Table] = true
+            tables = append(tables, dim.Table)
+            joins = append(joins, fmt.Sprintf("LEFT JOIN `%s` ON %s", dim.Table, dim.Join))
+        }
+        return dim, nil
+    }
+
+    for _, name := range query.Dimensions {
+        dim, err := useDimension(name)
+        if err != nil {
+            return "", nil, err
+        }
+        column := dimensionColumn(dim, base.Table)
+        selects = append(selects, fmt.Sprintf("%s AS `%s`", column, dim.Name))
+        groupBy = append(groupBy, column)
+    }
+
+    if query.TimeGrain != "" {
+        if !containsString(metricTimeGrains, query.TimeGrain) {
+            return "", nil, fmt.Errorf("%w: unsupported time grain %s", ErrInvalidMetricQuery, query.TimeGrain)
+        }
+        if base.TimeColumn == "" {
+            return "", nil, fmt.Errorf("%w: metric %s has no time column", ErrInvalidMetricQuery, base.Name)
+        }
+        column := fmt.Sprintf("date_trunc('%s', `%s`.`%s`)", query.TimeGrain, base.Table, base.TimeColumn)
+        selects = append(selects, fmt.Sprintf("%s AS `%s`", column, query.TimeGrain))
+        groupBy = append(groupBy, column)
+    }
+
+    for _, metric := range metrics {
+        selects = append(selects, fmt.Sprintf("%s AS `%s`", metric.Expression, metric.Name))
+    }
+
+    for _, filter := range base.Filters {
+        where = append(where, "("+filter+")")
+    }
+    if query.StartDate != "" || query.EndDate != "" {
+        if base.TimeColumn == "" {
+            return "", nil, fmt.Errorf("%w: metric %s has no time column", ErrInvalidMetricQuery, base.Name)
+        }
+        timeColumn := fmt.Sprintf("`%s`.`%s`", base.Table, base.TimeColumn)
+        if query.StartDate != "" {
+            if !metricDatePattern.MatchString(query.StartDate) {
+                return "", nil, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrInvalidMetricQuery)
+            }
+            where = append(where, fmt.Sprintf("%s >= '%s'", timeColumn, query.StartDate))
+        }
+        if query.EndDate != "" {
+            if !metricDatePattern.MatchString(query.EndDate) {
+                return "", nil, fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidMetricQuery)
+            }
+            where = append(where, fmt.Sprintf("%s < '%s'", timeColumn, query.EndDate))
+        }
+    }
+    for _, filter := range query.Filters {
+        dim, err := useDimension(filter.Dimension)
+        if err != nil {
+            return "", nil, err
+        }
+        condition, err := compileMetricFilter(dimensionColumn(dim, base.Table), filter)
+        if err != nil {
+            return "", nil, err
+        }
+        where = append(where, condition)
+    }
+
+    var b strings.Builder
+    b.WriteString("SELECT " + strings.Join(selects, ", "))
+    b.WriteString(fmt.Sprintf("\nFROM `%s`", base.Table))
+    for _, join := range joins {
+        b.WriteString("\n" + join)
+    }
+    if len(where) > 0 {
+        b.WriteString("\nWHERE " + strings.Join(where, " AND "))
+    }
+    if len(groupBy) > 0 {
+        b.WriteString("\nGROUP BY " + strings.Join(groupBy, ", "))
+        b.WriteString("\nORDER BY " + strings.Join(groupBy, ", "))
+    }
+    if query.Limit > 0 {
+        b.WriteString(fmt.Sprintf("\nLIMIT %d", query.Limit))
+    }
+    return b.String(), tables, nil
+}
+
+// FormatMetricCatalog 生成提示词中的指标和维度说明
+func (l *SemanticLayer) FormatMetricCatalog(metrics []MetricDefinition) string {
+    var b strings.Builder
+    var dimensions []string
+    // 基础表上的维度按第一个使用它的指标取值示例
+    dimensionBase := make(map[string]string)
+    b.WriteString("指标:\n")
+    for _, metric := range metrics {
+        b.WriteString(fmt.Sprintf("- %s", metric.Name))
+        if metric.Label != "" {
+            b.WriteString(fmt.Sprintf("（%s）", metric.Label))
+        }
+        if metric.Description != "" {
+            b.WriteString(": " + metric.Description)
+        }
+        b.WriteString(fmt.Sprintf(" 可用维度: %s", strings.Join(metric.Dimensions, ", ")))
+        if metric.TimeColumn != "" {
+            b.WriteString(" 支持时间粒度和时间范围")
+        }
+        b.WriteString("\n")
+        for _, name := range metric.Dimensions {
+            dimensions = appendUnique(dimensions, name)
+            if _, ok := dimensionBase[name]; !ok {
+                dimensionBase[name] = metric.Table
+            }
+        }
+    }
+    sort.Strings(dimensions)
+    b.WriteString("维度:\n")
+    for _, name := range dimensions {
+        dim := l.dimensions[name]
+        b.WriteString("- " + dim.Name)
+        if dim.Label != "" {
+            b.WriteString(fmt.Sprintf("（%s）", dim.Label))
+        }
+        if samples := FormatColumnSamples(dimensionTable(dim, dimensionBase[name]), dim.Column); samples != "" {
+            b.WriteString(" " + samples)
+        }
+        b.WriteString("\n")
+    }
+    return b.String()
+}
+
+func dimensionTable(dim *DimensionDefinition, baseTable string) string {
+    if dim.Table != "" {
+        return dim.Table
+    }
+    return baseTable
+}
+
+func dimensionColumn(dim *DimensionDefinition, baseTable string) string {
+    return fmt.Sprintf("`%s`.`%s`", dimensionTable(dim, baseTable), dim.Column)
+}
+
+// compileMetricFilter 过滤值统一作为字符串字面量转义，不拼接原始SQL
+func compileMetricFilter(column string, filter MetricFilter) (string, error) {
+    op := strings.ToLower(strings.TrimSpace(filter.Operator))
+    if op == "" {
+        op = "="
+    }
+    if !containsString(metricOperators, op) {
+        return "", fmt.Errorf("%w: unsupported operator %s", ErrInvalidMetricQuery, filter.Operator)
+    }
+    if len(filter.Values) == 0 {
+        return "", fmt.Errorf("%w: filter on %s has no values", ErrInvalidMetricQuery, filter.Dimension)
+    }
+
+    if op == "in" || op == "not in" {
+        quoted := make([]string, len(filter.Values))
+        for i, v := range filter.Values {
+            quoted[i] = quoteSQLString(v)
+        }
+        return fmt.Sprintf("%s %s (%s)", column, strings.ToUpper(op), strings.Join(quoted, ", ")), nil
+    }
+    if len(filter.Values) != 1 {
+        return "", fmt.Errorf("%w: operator %s takes exactly one value", ErrInvalidMetricQuery, op)
+    }
+    return fmt.Sprintf("%s %s %s", column, strings.ToUpper(op), quoteSQLString(filter.Values[0])), nil
+}
+
+// quoteSQLString 转义为SQL字符串字面量
+func quoteSQLString(value string) string {
+    value = strings.ReplaceAll(value, `\`, `\\`)
+    value = strings.ReplaceAll(value, `'`, `''`)
+    return "'" + value + "'"
+}
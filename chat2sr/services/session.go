@@ -0,0 +1,234 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// 提示词中每轮结果摘要最多列出的字段数
+const sessionSummaryColumns = 10
+
+// Session 多轮对话，保存之前的问题、生成的SQL、使用的表和结果摘要
+type Session struct {
+    ID        string        `json:"id"`
+    User      string        `json:"user,omitempty"`
+    Turns     []SessionTurn `json:"turns"`
+    CreatedAt time.Time     `json:"created_at"`
+    UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// SessionTurn 对话中的一轮问答
+type SessionTurn struct {
+    Question  string    `json:"question"`
+    SQL       string    `json:"sql"`
+    Tables    []string  `json:"tables,omitempty"`
+    ResultID  string    `json:"result_id,omitempty"`
+    Summary   string    `json:"summary,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+var sessionMu sync.Mutex
+
+func sessionDir() string {
+    return filepath.Join(config.AppConfig.DataDir, "sessions")
+}
+
+func sessionPath(id string) string {
+    return filepath.Join(sessionDir(), id+".json")
+}
+
+// CreateSession 新建对话
+func CreateSession(user string) (*Session, error) {
+    now := time.Now()
+    session := &Session{
+        ID:        newID(),
+        User:      strings.TrimSpace(user),
+        Turns:     []SessionTurn{},
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    if err := saveJSONFile(sessionPath(session.ID), session); err != nil {
+        return nil, err
+    }
+    return session, nil
+}
+
+// GetSession 读取对话
+func GetSession(id string) (*Session, error) {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    return loadSessionLocked(id)
+}
+
+// DeleteSession 删除对话
+func DeleteSession(id string) error {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    if !resultIDRegex.MatchString(id) {
+        return ErrSessionNotFound
+    }
+    if err := os.Remove(sessionPath(id)); err != nil {
+        if os.IsNotExist(err) {
+            return ErrSessionNotFound
+        }
+        return err
+    }
+    return nil
+}
+
+// AppendSessionTurn 记录新一轮问答，超过历史上限时丢弃最早的轮次
+func AppendSessionTurn(id string, turn SessionTurn) (*Session, error) {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    session, err := loadSessionLocked(id)
+    if err != nil {
+        return nil, err
+    }
+    turn.CreatedAt = time.Now()
+    session.Turns = append(session.Turns, turn)
+    if limit := config.AppConfig.SessionMaxTurns; limit > 0 && len(session.Turns) > limit {
+        session.Turns = session.Turns[len(session.Turns)-limit:]
+    }
+    session.UpdatedAt = turn.CreatedAt
+    if err := saveJSONFile(sessionPath(id), session); err != nil {
+        return nil, err
+    }
+    return session, nil
+}
+
+// RecordSessionResult 将执行结果的摘要记录到生成该SQL的最近一轮，没有对应轮次时忽略
+func RecordSessionResult(id, sqlQuery, resultID string, columns []string, rowCount int) error {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    session, err := loadSessionLocked(id)
+    if err != nil {
+        return err
+    }
+    sqlQuery = strings.TrimSpace(sqlQuery)
+    for i := len(session.Turns) - 1; i >= 0; i-- {
+        if strings.TrimSpace(session.Turns[i].SQL) != sqlQuery {
+            continue
+        }
+        session.Turns[i].ResultID = resultID
+        session.Turns[i].Summary = summarizeResult(columns, rowCount)
+        session.UpdatedAt = time.Now()
+        return saveJSONFile(sessionPath(id), session)
+    }
+    return nil
+}
+
+// LastTurn 对话的最近一轮，没有历史时返回 nil
+func (s *Session) LastTurn() *SessionTurn {
+    if len(s.Turns) == 0 {
+        return nil
+    }
+    return &s.Turns[len(s.Turns)-1]
+}
+
+// FormatSessionHistory 生成提示词中的对话历史
+func FormatSessionHistory(session *Session) string {
+    if session == nil || len(session.Turns) == 0 {
+        return ""
+    }
+    var b strings.Builder
+    b.WriteString("对话历史（当前问题可能是对上一轮查询的追问）：\n")
+    for i, turn := range session.Turns {
+        b.WriteString(fmt.Sprintf("第%d轮问题: %s\n", i+1, turn.Question))
+        if len(turn.Tables) > 0 {
+            b.WriteString("使用的表: " + strings.Join(turn.Tables, ",") + "\n")
+        }
+        b.WriteString("SQL: " + turn.SQL + "\n")
+        if turn.Summary != "" {
+            b.WriteString("结果: " + turn.Summary + "\n")
+        }
+    }
+    return b.String()
+}
+
+func summarizeResult(columns []string, rowCount int) string {
+    shown := columns
+    if len(shown) > sessionSummaryColumns {
+        shown = shown[:sessionSummaryColumns]
+    }
+    summary := fmt.Sprintf("返回 %d 行，字段: %s", rowCount, strings.Join(shown, ", "))
+    if len(columns) > len(shown) {
+        summary += fmt.Sprintf(" 等 %d 个", len(columns))
+    }
+    return summary
+}
+
+// loadSessionLocked 从磁盘读取对话，调用方需持有锁
+func loadSessionLocked(id string) (*Session, error) {
+    if !resultIDRegex.MatchString(id) {
+        return nil, ErrSessionNotFound
+    }
+    if _, err := os.Stat(sessionPath(id)); os.IsNotExist(err) {
+        return nil, ErrSessionNotFound
+    }
+    var session Session
+    if err := loadJSONFile(sessionPath(id), &session); err != nil {
+        return nil, err
+    }
+    return &session, nil
+}
+
+// StartSessionCleanup 定期删除超过保留期未更新的对话。未指定 session_id 的查询会自动新建对话，
+// 其中没有追问过（不超过一轮）的对话按较短的 SESSION_SINGLE_TURN_RETENTION 删除，避免一次性调用堆积
+func StartSessionCleanup() {
+    retention := config.AppConfig.SessionRetention
+    singleTurnRetention := config.AppConfig.SessionSingleTurnTTL
+    go func() {
+        ticker := time.NewTicker(time.Hour)
+        defer ticker.Stop()
+        for {
+            removeExpiredSessions(retention, singleTurnRetention)
+            <-ticker.C
+        }
+    }()
+}
+
+func removeExpiredSessions(retention, singleTurnRetention time.Duration) {
+    entries, err := os.ReadDir(sessionDir())
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("Error reading session directory: %s", err.Error())
+        }
+        return
+    }
+
+    now := time.Now()
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    for _, entry := range entries {
+        info, err := entry.Info()
+        if err != nil {
+            continue
+        }
+        age := now.Sub(info.ModTime())
+        if age <= retention {
+            if singleTurnRetention <= 0 || age <= singleTurnRetention {
+                continue
+            }
+            session, err := loadSessionLocked(strings.TrimSuffix(entry.Name(), ".json"))
+            if err != nil || len(session.Turns) > 1 {
+                continue
+            }
+        }
+        if err := os.Remove(filepath.Join(sessionDir(), entry.Name())); err != nil {
+            log.Printf("Error removing expired session %s: %s", entry.Name(), err.Error())
+            continue
+        }
+        log.Printf("Session %s expired and removed", strings.TrimSuffix(entry.Name(), ".json"))
+    }
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+    "os"
+    "testing"
+    "time"
+    "chat2sr/config"
+)
+
+func TestRemoveExpiredSessions(t *testing.T) {
+    config.AppConfig.DataDir = t.TempDir()
+
+    newSession := func(turns int, age time.Duration) string {
+        session, err := CreateSession("")
+        if err != nil {
+            t.Fatalf("CreateSession error: %v", err)
+        }
+        for i := 0; i < turns; i++ {
+            if _, err := AppendSessionTurn(session.ID, SessionTurn{Question: "q", SQL: "SELECT 1"}); err != nil {
+                t.Fatalf("AppendSessionTurn error: %v", err)
+            }
+        }
+        modified := time.Now().Add(-age)
+        if err := os.Chtimes(sessionPath(session.ID), modified, modified); err != nil {
+            t.Fatalf("Chtimes error: %v", err)
+        }
+        return session.ID
+    }
+
+    recentSingle := newSession(1, time.Hour)
+    staleSingle := newSession(1, 48*time.Hour)
+    staleEmpty := newSession(0, 48*time.Hour)
+    staleFollowUp := newSession(2, 48*time.Hour)
+    expired := newSession(2, 200*time.Hour)
+
+    removeExpiredSessions(7*24*time.Hour, 24*time.Hour)
+
+    for id, kept := range map[string]bool{
+        recentSingle:  true,
+        staleSingle:   false,
+        staleEmpty:    false,
+        staleFollowUp: true,
+        expired:       false,
+    } {
+        _, err := GetSession(id)
+        if kept && err != nil {
+            t.Errorf("session %s removed, want kept: %v", id, err)
+        }
+        if !kept && err == nil {
+            t.Errorf("session %s kept, want removed", id)
+        }
+    }
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "chat2sr/config"
+)
+
+var ErrSQLEditInvalid = errors.New("invalid SQL edit request")
+
+// SQLEdit 按修改要求改写后的SQL及与原SQL的差异
+type SQLEdit struct {
+    SQL     string     `json:"sql"`
+    Diff    string     `json:"diff"`
+    Changed bool       `json:"changed"`
+    Tables  []string   `json:"tables"`
+    Joins   []JoinEdge `json:"joins,omitempty"`
+}
+
+// EditSQL 按自然语言的修改要求（如"加上按渠道分组"、"改成近30天"）改写已有SQL，
+// 只提供原SQL中用到的表的结构，改写结果与新生成的SQL做同样的校验
+func EditSQL(sqlText, instruction string) (*SQLEdit, error) {
+    sqlText = strings.TrimSpace(sqlText)
+    instruction = strings.TrimSpace(instruction)
+    if sqlText == "" || instruction == "" {
+        return nil, fmt.Errorf("%w: sql and instruction are required", ErrSQLEditInvalid)
+    }
+
+    tables := ExtractSQLTables(sqlText)
+    _, joins := CurrentJoinGraph().ConnectTables(tables)
+    // 字段按修改要求和原SQL一起挑选，保证原SQL引用的字段和要求中提到的字段都在提示词中
+    question := instruction + " " + sqlText
+    sampleBudget := config.AppConfig.PromptSampleTokens
+    schemaBudget := SchemaTokenBudget(len(tables))
+    var schemaDesc strings.Builder
+    for _, name := range tables {
+        schema, err := findTable(name)
+        if err != nil {
+            // WITH 子句的名称或子查询别名
+            continue
+        }
+        schemaDesc.WriteString(fmt.Sprintf("%s表字段", schema.Name))
+        if schema.Comment != "" {
+            schemaDesc.WriteString(fmt.Sprintf("（%s）", schema.Comment))
+        }
+        schemaDesc.WriteString("：\n")
+        promptColumns := SelectPromptColumns(schema, question, schemaBudget)
+        for _, col := range promptColumns.Columns {
+            line := fmt.Sprintf("- %s %s", col.Name, col.Type)
+            if samples := FormatColumnSamples(schema.Name, col.Name); samples != "" {
+                if cost := EstimateTokens(samples); cost <= sampleBudget {
+                    line += " " + samples
+                    sampleBudget -= cost
+                }
+            }
+            if col.Comment != "" {
+                line += " 说明: " + col.Comment
+            }
+            schemaDesc.WriteString(line + "\n")
+        }
+        schemaDesc.WriteString(FormatOmittedColumns(promptColumns.Omitted))
+        schemaDesc.WriteString(FormatTableDetails(schema.Details))
+        schemaDesc.WriteString("\n")
+    }
+    schemaDesc.WriteString(FormatJoinConditions(joins))
+    schemaDesc.WriteString(FormatGlossaryDefinitions(MatchGlossary(instruction)))
+
+    prompt := fmt.Sprintf(`你是一个SQL专家。请按照修改要求改写下面的SQL。
+要求：
+1. 只做修改要求中提到的改动，其余部分（字段、过滤条件、口径、格式）保持原样
+2. 只能使用下面表结构中实际存在的字段，字段给出了可选值时使用其中的实际取值
+3. 生成的SQL必须与 StarRocks 的语法完全匹配
+4. 修改要求中出现业务术语时，按照给出的业务术语定义处理
+5. 只返回改写后的完整SQL，不要包含任何解释，不要使用markdown格式
+
+表结构:
+%s
+原SQL:
+%s
+
+修改要求: %s`, schemaDesc.String(), sqlText, instruction)
+
+    response, err := ProcessQuery(prompt)
+    if err != nil {
+        return nil, err
+    }
+    edited := cleanSQLResponse(response)
+    if err := ValidateSQL(edited); err != nil {
+        return nil, err
+    }
+
+    // 是否有改动与差异一致，只有换行符不同时不算改动
+    diff := UnifiedDiff(sqlText, edited, "original.sql", "edited.sql")
+    return &SQLEdit{
+        SQL:     edited,
+        Diff:    diff,
+        Changed: diff != "",
+        Tables:  ExtractSQLTables(edited),
+        Joins:   joins,
+    }, nil
+}
+
+// cleanSQLResponse 去掉模型回复中可能的markdown代码块标记
+func cleanSQLResponse(response string) string {
+    sql := strings.TrimSpace(response)
+    sql = strings.TrimPrefix(sql, "```sql")
+    sql = strings.TrimPrefix(sql, "```")
+    sql = strings.TrimSuffix(sql, "```")
+    return strings.TrimSpace(sql)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// 解释SQL使用的语言
+const (
+    ExplainLangZh = "zh"
+    ExplainLangEn = "en"
+)
+
+var ErrExplainInvalid = errors.New("invalid explain request")
+
+// ExplainStep 解释中的一个步骤
+type ExplainStep struct {
+    Kind   string `json:"kind"`
+    Title  string `json:"title"`
+    Detail string `json:"detail"`
+}
+
+// ExplainedColumn 输出列的业务含义
+type ExplainedColumn struct {
+    Name    string `json:"name"`
+    Meaning string `json:"meaning"`
+}
+
+// ExplainedTable SQL中使用的表及其在目录中的说明
+type ExplainedTable struct {
+    Name    string `json:"name"`
+    Comment string `json:"comment,omitempty"`
+    Known   bool   `json:"known"`
+}
+
+// SQLExplanation SQL的逐步说明，表和关联条件直接从SQL中解析，其余由模型结合表结构说明生成
+type SQLExplanation struct {
+    Language string            `json:"language"`
+    Summary  string            `json:"summary"`
+    Steps    []ExplainStep     `json:"steps"`
+    Columns  []ExplainedColumn `json:"columns"`
+    Tables   []ExplainedTable  `json:"tables"`
+    Joins    []JoinEdge        `json:"joins,omitempty"`
+    Caveats  []string          `json:"caveats,omitempty"`
+}
+
+// ExplainSQL 用中文或英文逐步解释SQL：用到哪些表、如何关联、过滤条件、分组方式以及每个输出列的含义
+func ExplainSQL(sqlText, language string) (*SQLExplanation, error) {
+    sqlText = strings.TrimSpace(sqlText)
+    if sqlText == "" {
+        return nil, fmt.Errorf("%w: sql is required", ErrExplainInvalid)
+    }
+    language = strings.ToLower(strings.TrimSpace(language))
+    switch language {
+    case "":
+        language = ExplainLangZh
+    case ExplainLangZh, ExplainLangEn:
+    default:
+        return nil, fmt.Errorf("%w: unsupported language %s", ErrExplainInvalid, language)
+    }
+
+    explanation := &SQLExplanation{Language: language, Joins: extractJoinEdges(sqlText)}
+    var schemaDesc strings.Builder
+    for _, name := range ExtractSQLTables(sqlText) {
+        table, err := findTable(name)
+        if err != nil {
+            // 子查询别名或目录中不存在的表只列出名称
+            explanation.Tables = append(explanation.Tables, ExplainedTable{Name: name})
+            continue
+        }
+        explanation.Tables = append(explanation.Tables, ExplainedTable{Name: name, Comment: table.Comment, Known: true})
+        schemaDesc.WriteString(formatExplainTable(table, sqlText))
+    }
+    for _, entry := range MatchGlossary(sqlText) {
+        schemaDesc.WriteString(fmt.Sprintf("术语 %s: %s\n", entry.Term, firstNonEmpty(entry.Description, entry.Expression)))
+    }
+
+    response, err := ProcessQuery(buildExplainPrompt(sqlText, language, schemaDesc.String()))
+    if err != nil {
+        return nil, err
+    }
+    var parsed struct {
+        Summary string            `json:"summary"`
+        Steps   []ExplainStep     `json:"steps"`
+        Columns []ExplainedColumn `json:"columns"`
+        Caveats []string          `json:"caveats"`
+    }
+    if err := json.Unmarshal([]byte(extractJSONObject(response)), &parsed); err != nil {
+        return nil, fmt.Errorf("failed to parse SQL explanation: %v", err)
+    }
+    explanation.Summary = strings.TrimSpace(parsed.Summary)
+    explanation.Steps = parsed.Steps
+    explanation.Columns = parsed.Columns
+    explanation.Caveats = trimStrings(parsed.Caveats)
+    return explanation, nil
+}
+
+// formatExplainTable 列出表的说明和SQL中引用到的字段，未引用的字段不放入提示词
+func formatExplainTable(table *TableSchema, sqlText string) string {
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("表 %s", table.Name))
+    if table.Comment != "" {
+        b.WriteString(fmt.Sprintf(" (说明: %s)", table.Comment))
+    }
+    b.WriteString("\n")
+    sqlLower := strings.ToLower(sqlText)
+    for _, col := range table.Columns {
+        if !containsTerm(sqlLower, strings.ToLower(col.Name)) {
+            continue
+        }
+        line := fmt.Sprintf("- %s (%s)", col.Name, col.Type)
+        if col.Comment != "" {
+            line += " 说明: " + col.Comment
+        }
+        if samples := FormatColumnSamples(table.Name, col.Name); samples != "" {
+            line += " " + samples
+        }
+        b.WriteString(line + "\n")
+    }
+    return b.String()
+}
+
+func buildExplainPrompt(sqlText, language, schemaDesc string) string {
+    languageName := "中文"
+    if language == ExplainLangEn {
+        languageName = "English"
+    }
+    if schemaDesc == "" {
+        schemaDesc = "（没有找到相关表的说明）\n"
+    }
+    return fmt.Sprintf(`请用%s向不懂SQL的业务人员逐步解释下面的SQL，让他们能判断结果是否可信。
+要求：
+1. 按执行顺序说明：使用了哪些表、表之间如何关联、有哪些过滤条件、如何分组和聚合、如何排序和限制行数，没有的步骤省略
+2. 结合表和字段的说明解释业务含义，不要只复述SQL
+3. 逐个解释输出列的含义，包括计算方式
+4. 如果有容易误解的地方（如去重、空值、时间范围的开闭、关联可能导致重复计数），在 caveats 中指出
+5. 只返回一个JSON对象，不要包含其他内容，格式如下：
+{"summary": "一句话概括", "steps": [{"kind": "tables|joins|filters|grouping|order|output", "title": "步骤标题", "detail": "说明"}], "columns": [{"name": "输出列名", "meaning": "含义"}], "caveats": ["注意事项"]}
+
+表结构说明:
+%s
+SQL:
+%s`, languageName, schemaDesc, sqlText)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+var (
+    ErrSQLInvalid = errors.New("invalid SQL")
+
+    // 只读查询之外的语句关键字
+    sqlWritePattern = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|upsert|drop|alter|create|truncate|rename|grant|revoke|export|outfile)\b`)
+    // WITH 子句定义的临时结果集名称
+    sqlCTEPattern = regexp.MustCompile("(?i)(?:\\bwith|,)\\s*(?:recursive\\s+)?`?(\\w+)`?\\s+as\\s*\\(")
+)
+
+// ValidateSQL 校验模型生成或修改的SQL：只能是单条 SELECT / WITH 查询，括号成对，引用的表在表结构目录中存在
+func ValidateSQL(sqlText string) error {
+    stripped, err := stripSQLLiterals(sqlText)
+    if err != nil {
+        return err
+    }
+    stripped = strings.TrimSpace(stripped)
+    stripped = strings.TrimSpace(strings.TrimSuffix(stripped, ";"))
+    if stripped == "" {
+        return fmt.Errorf("%w: empty statement", ErrSQLInvalid)
+    }
+    if strings.Contains(stripped, ";") {
+        return fmt.Errorf("%w: multiple statements are not allowed", ErrSQLInvalid)
+    }
+    first := strings.ToLower(strings.Fields(stripped)[0])
+    if first != "select" && first != "with" && !strings.HasPrefix(first, "(") {
+        return fmt.Errorf("%w: only SELECT queries are allowed", ErrSQLInvalid)
+    }
+    if m := sqlWritePattern.FindString(stripped); m != "" {
+        return fmt.Errorf("%w: %s is not allowed", ErrSQLInvalid, strings.ToUpper(m))
+    }
+    depth := 0
+    for _, r := range stripped {
+        switch r {
+        case '(':
+            depth++
+        case ')':
+            depth--
+        }
+        if depth < 0 {
+            break
+        }
+    }
+    if depth != 0 {
+        return fmt.Errorf("%w: unbalanced parentheses", ErrSQLInvalid)
+    }
+
+    // 表结构目录不可用时不校验表名
+    tables, err := CatalogTables()
+    if err != nil {
+        return nil
+    }
+    known := make(map[string]bool, len(tables))
+    for _, table := range tables {
+        known[strings.ToLower(table.Name)] = true
+    }
+    for _, m := range sqlCTEPattern.FindAllStringSubmatch(stripped, -1) {
+        known[strings.ToLower(m[1])] = true
+    }
+    var unknown []string
+    for _, table := range ExtractSQLTables(stripped) {
+        if !known[strings.ToLower(table)] {
+            unknown = append(unknown, table)
+        }
+    }
+    if len(unknown) > 0 {
+        return fmt.Errorf("%w: unknown tables %s", ErrSQLInvalid, strings.Join(unknown, ", "))
+    }
+    return nil
+}
+
+// stripSQLLiterals 将字符串常量和注释替换为空格，避免其中的关键字和分号影响校验
+func stripSQLLiterals(sqlText string) (string, error) {
+    var b strings.Builder
+    for i := 0; i < len(sqlText); i++ {
+        c := sqlText[i]
+        switch {
+        case c == '\'' || c == '"':
+            end := i + 1
+            for ; end < len(sqlText); end++ {
+                if sqlText[end] == '\\' {
+                    end++
+                    continue
+                }
+                if sqlText[end] == c {
+                    // 连续两个引号表示转义
+                    if end+1 < len(sqlText) && sqlText[end+1] == c {
+                        end++
+                        continue
+                    }
+                    break
+                }
+            }
+            if end >= len(sqlText) {
+                return "", fmt.Errorf("%w: unterminated string literal", ErrSQLInvalid)
+            }
+            b.WriteString(" '' ")
+            i = end
+        case c == '-' && strings.HasPrefix(sqlText[i:], "--"), c == '#':
+            for i < len(sqlText) && sqlText[i] != '\n' {
+                i++
+            }
+            b.WriteByte('\n')
+        case c == '/' && strings.HasPrefix(sqlText[i:], "/*"):
+            end := strings.Index(sqlText[i+2:], "*/")
+            if end < 0 {
+                return "", fmt.Errorf("%w: unterminated comment", ErrSQLInvalid)
+            }
+            i += 2 + end + 1
+            b.WriteByte(' ')
+        default:
+            b.WriteByte(c)
+        }
+    }
+    return b.String(), nil
+}
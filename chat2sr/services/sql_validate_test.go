@@ -0,0 +1,44 @@
+package services
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestValidateSQL(t *testing.T) {
+    tests := []struct {
+        name  string
+        sql   string
+        valid bool
+    }{
+        {"select", "SELECT a FROM t", true},
+        {"trailing semicolon", "SELECT a FROM t;", true},
+        {"cte", "WITH x AS (SELECT 1) SELECT * FROM x", true},
+        {"parenthesized union", "(SELECT 1) UNION (SELECT 2)", true},
+        {"keyword in literal", "SELECT a FROM t WHERE b = 'x; drop table y'", true},
+        {"keyword in comment", "SELECT a FROM t -- delete later\n", true},
+        {"keyword in block comment", "SELECT a FROM t /* insert */", true},
+        {"keyword as part of column", "SELECT update_time, is_delete FROM t", true},
+        {"from inside functions", "SELECT EXTRACT(YEAR FROM order_date), TRIM(BOTH ' ' FROM name) FROM orders", true},
+        {"escaped quote", "SELECT a FROM t WHERE b = 'it''s'", true},
+        {"empty", "  ", false},
+        {"multiple statements", "SELECT 1; DROP TABLE t", false},
+        {"delete", "DELETE FROM t", false},
+        {"write inside select", "SELECT * FROM t INTO OUTFILE '/tmp/x'", false},
+        {"unbalanced parentheses", "SELECT COUNT(( FROM t", false},
+        {"closing parenthesis first", "SELECT a) FROM (t", false},
+        {"unterminated literal", "SELECT 'abc FROM t", false},
+        {"unterminated comment", "SELECT a FROM t /* x", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := ValidateSQL(tt.sql)
+            if tt.valid && err != nil {
+                t.Errorf("ValidateSQL(%q) = %v, want nil", tt.sql, err)
+            }
+            if !tt.valid && !errors.Is(err, ErrSQLInvalid) {
+                t.Errorf("ValidateSQL(%q) = %v, want ErrSQLInvalid", tt.sql, err)
+            }
+        })
+    }
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+    "chat2sr/config"
+)
+
+// TableDetails StarRocks 表的模型、分区、分桶等信息
+type TableDetails struct {
+    Model             string             `json:"model,omitempty"`
+    KeyColumns        []string           `json:"key_columns,omitempty"`
+    PartitionColumns  []string           `json:"partition_columns,omitempty"`
+    PartitionRange    string             `json:"partition_range,omitempty"`
+    DistributionKeys  []string           `json:"distribution_keys,omitempty"`
+    RowCount          int64              `json:"row_count"`
+    MaterializedViews []MaterializedView `json:"materialized_views,omitempty"`
+
+    // 建表信息和分区范围的获取时间
+    fetchedAt time.Time
+}
+
+// MaterializedView 异步物化视图
+type MaterializedView struct {
+    Name       string `json:"name"`
+    Definition string `json:"definition"`
+}
+
+var (
+    keyModelRegex     = regexp.MustCompile("(?i)\\b(DUPLICATE|AGGREGATE|UNIQUE|PRIMARY)\\s+KEY\\s*\\(([^)]*)\\)")
+    partitionRegex    = regexp.MustCompile("(?i)\\bPARTITION\\s+BY\\s+(.*)")
+    quotedRegex       = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+    distributionRegex = regexp.MustCompile("(?i)\\bDISTRIBUTED\\s+BY\\s+HASH\\s*\\(([^)]*)\\)")
+    partitionKeyRegex = regexp.MustCompile(`keys: \[([^\]]*)\]`)
+    identifierRegex   = regexp.MustCompile("`?([A-Za-z_][A-Za-z0-9_]*)`?")
+)
+
+// 分区定义中的关键字及表达式分区常见的函数名，解析分区字段时需要跳过
+var partitionKeywords = map[string]bool{
+    "range": true, "list": true, "partition": true, "values": true,
+    "date_trunc": true, "time_slice": true, "str2date": true,
+}
+
+// GetTableDetails 通过 SHOW CREATE TABLE 和 information_schema 获取表的模型、分区、分桶、行数及物化视图。
+// reuse 为上次加载的结果，其中未超过 TABLE_DETAIL_TTL 的表沿用建表信息和分区范围，其余的表由有限个worker并发获取
+func GetTableDetails(tableNames []string, reuse map[string]*TableDetails) (map[string]*TableDetails, error) {
+    dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+        config.AppConfig.DBUser,
+        config.AppConfig.DBPassword,
+        config.AppConfig.DBHost,
+        config.AppConfig.DBPort,
+        config.AppConfig.DBName)
+
+    db, err := sql.Open("mysql", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to database: %v", err)
+    }
+    defer db.Close()
+
+    details := make(map[string]*TableDetails, len(tableNames))
+    var stale []string
+    for _, name := range tableNames {
+        if previous, ok := reuse[name]; ok && time.Since(previous.fetchedAt) < config.AppConfig.TableDetailTTL {
+            details[name] = &TableDetails{
+                Model:            previous.Model,
+                KeyColumns:       previous.KeyColumns,
+                PartitionColumns: previous.PartitionColumns,
+                PartitionRange:   previous.PartitionRange,
+                DistributionKeys: previous.DistributionKeys,
+                fetchedAt:        previous.fetchedAt,
+            }
+            continue
+        }
+        details[name] = &TableDetails{}
+        stale = append(stale, name)
+    }
+
+    workers := config.AppConfig.TableDetailWorkers
+    if workers <= 0 {
+        workers = 1
+    }
+    db.SetMaxOpenConns(workers)
+    names := make(chan string)
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for name := range names {
+                loadTableDDL(db, name, details[name])
+            }
+        }()
+    }
+    for _, name := range stale {
+        names <- name
+    }
+    close(names)
+    wg.Wait()
+    if len(stale) > 0 {
+        log.Printf("Loaded DDL details for %d of %d tables", len(stale), len(tableNames))
+    }
+
+    if err := loadRowCounts(db, details); err != nil {
+        log.Printf("Error getting row counts: %s", err.Error())
+    }
+    if err := loadMaterializedViews(db, details); err != nil {
+        log.Printf("Error getting materialized views: %s", err.Error())
+    }
+
+    return details, nil
+}
+
+// loadTableDDL 解析单张表的建表语句，有分区字段时读取分区范围
+func loadTableDDL(db *sql.DB, name string, detail *TableDetails) {
+    detail.fetchedAt = time.Now()
+
+    var table, ddl string
+    if err := db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", name)).Scan(&table, &ddl); err != nil {
+        // 视图等对象的 SHOW CREATE 返回列数不同，跳过即可
+        log.Printf("Skipping DDL details for %s: %s", name, err.Error())
+        return
+    }
+    parseCreateTable(ddl, detail)
+
+    if len(detail.PartitionColumns) > 0 {
+        partitionRange, err := getPartitionRange(db, name)
+        if err != nil {
+            log.Printf("Error getting partitions for %s: %s", name, err.Error())
+        }
+        detail.PartitionRange = partitionRange
+    }
+}
+
+// parseCreateTable 从建表语句中解析表模型、分区字段和分桶字段
+func parseCreateTable(ddl string, detail *TableDetails) {
+    if m := keyModelRegex.FindStringSubmatch(ddl); m != nil {
+        detail.Model = strings.ToUpper(m[1]) + " KEY"
+        detail.KeyColumns = parseIdentifiers(m[2])
+    }
+
+    if m := partitionRegex.FindStringSubmatch(ddl); m != nil {
+        // 只取分区定义本身，去掉后面的分区列表和字符串常量
+        expr := m[1]
+        if idx := strings.Index(strings.ToUpper(expr), "PARTITION "); idx != -1 {
+            expr = expr[:idx]
+        }
+        expr = quotedRegex.ReplaceAllString(expr, "")
+        for _, ident := range parseIdentifiers(expr) {
+            if !partitionKeywords[strings.ToLower(ident)] {
+                detail.PartitionColumns = append(detail.PartitionColumns, ident)
+            }
+        }
+    }
+
+    if m := distributionRegex.FindStringSubmatch(ddl); m != nil {
+        detail.DistributionKeys = parseIdentifiers(m[1])
+    }
+}
+
+// parseIdentifiers 解析逗号分隔的字段列表，去掉反引号及字符串常量
+func parseIdentifiers(list string) []string {
+    var result []string
+    for _, part := range strings.Split(list, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" || strings.HasPrefix(part, "'") || strings.HasPrefix(part, "\"") {
+            continue
+        }
+        for _, m := range identifierRegex.FindAllStringSubmatch(part, -1) {
+            result = append(result, m[1])
+        }
+    }
+    return result
+}
+
+// getPartitionRange 通过 SHOW PARTITIONS 获取最早和最晚分区的范围
+func getPartitionRange(db *sql.DB, tableName string) (string, error) {
+    rows, err := db.Query(fmt.Sprintf("SHOW PARTITIONS FROM `%s`", tableName))
+    if err != nil {
+        return "", err
+    }
+    defer rows.Close()
+
+    columns, err := rows.Columns()
+    if err != nil {
+        return "", err
+    }
+    rangeIdx := -1
+    for i, col := range columns {
+        if strings.EqualFold(col, "Range") {
+            rangeIdx = i
+            break
+        }
+    }
+    if rangeIdx == -1 {
+        return "", nil
+    }
+
+    var first, last string
+    for rows.Next() {
+        values := make([]sql.NullString, len(columns))
+        pointers := make([]interface{}, len(columns))
+        for i := range values {
+            pointers[i] = &values[i]
+        }
+        if err := rows.Scan(pointers...); err != nil {
+            return "", err
+        }
+
+        keys := partitionKeyRegex.FindAllStringSubmatch(values[rangeIdx].String, -1)
+        if len(keys) < 2 {
+            continue
+        }
+        if first == "" {
+            first = keys[0][1]
+        }
+        last = keys[1][1]
+    }
+    if err := rows.Err(); err != nil {
+        return "", err
+    }
+
+    if first == "" {
+        return "", nil
+    }
+    return fmt.Sprintf("[%s, %s)", first, last), nil
+}
+
+// loadRowCounts 从 information_schema.tables 读取预估行数
+func loadRowCounts(db *sql.DB, details map[string]*TableDetails) error {
+    rows, err := db.Query(`
+        SELECT
+            TABLE_NAME,
+            TABLE_ROWS
+        FROM
+            INFORMATION_SCHEMA.TABLES
+        WHERE
+            TABLE_SCHEMA = ?
+    `, config.AppConfig.DBName)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var name string
+        var count sql.NullInt64
+        if err := rows.Scan(&name, &count); err != nil {
+            return err
+        }
+        if detail, ok := details[name]; ok {
+            detail.RowCount = count.Int64
+        }
+    }
+    return rows.Err()
+}
+
+// loadMaterializedViews 读取异步物化视图，并挂到定义中引用的基表上
+func loadMaterializedViews(db *sql.DB, details map[string]*TableDetails) error {
+    rows, err := db.Query(`
+        SELECT
+            TABLE_NAME,
+            MATERIALIZED_VIEW_DEFINITION
+        FROM
+            INFORMATION_SCHEMA.MATERIALIZED_VIEWS
+        WHERE
+            TABLE_SCHEMA = ?
+    `, config.AppConfig.DBName)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    var views []MaterializedView
+    for rows.Next() {
+        var name string
+        var definition sql.NullString
+        if err := rows.Scan(&name, &definition); err != nil {
+            return err
+        }
+        views = append(views, MaterializedView{Name: name, Definition: definition.String})
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    if len(views) == 0 {
+        return nil
+    }
+
+    for table, detail := range details {
+        tableRegex := regexp.MustCompile("(?i)[`.\\s]" + regexp.QuoteMeta(table) + "(?:[`\\s,)]|$)")
+        for _, view := range views {
+            if view.Name != table && tableRegex.MatchString(view.Definition) {
+                detail.MaterializedViews = append(detail.MaterializedViews, view)
+            }
+        }
+    }
+    return nil
+}
+
+// modelNames 表模型的中文说明
+var modelNames = map[string]string{
+    "DUPLICATE KEY": "明细模型",
+    "AGGREGATE KEY": "聚合模型",
+    "UNIQUE KEY":    "更新模型",
+    "PRIMARY KEY":   "主键模型",
+}
+
+// FormatTableDetails 将表的 StarRocks 特有信息格式化为提示词片段
+func FormatTableDetails(detail *TableDetails) string {
+    if detail == nil {
+        return ""
+    }
+
+    var b strings.Builder
+    if detail.Model != "" {
+        b.WriteString(fmt.Sprintf("表模型: %s %s (%s)\n", modelNames[detail.Model], detail.Model, strings.Join(detail.KeyColumns, ", ")))
+    }
+    if len(detail.PartitionColumns) > 0 {
+        b.WriteString(fmt.Sprintf("分区字段: %s", strings.Join(detail.PartitionColumns, ", ")))
+        if detail.PartitionRange != "" {
+            b.WriteString(fmt.Sprintf(" 分区范围: %s", detail.PartitionRange))
+        }
+        b.WriteString("（查询时应在WHERE中对分区字段加过滤条件）\n")
+    }
+    if len(detail.DistributionKeys) > 0 {
+        b.WriteString(fmt.Sprintf("分桶字段: %s\n", strings.Join(detail.DistributionKeys, ", ")))
+    }
+    if detail.RowCount > 0 {
+        b.WriteString(fmt.Sprintf("预估行数: %d\n", detail.RowCount))
+    }
+    for _, mv := range detail.MaterializedViews {
+        b.WriteString(fmt.Sprintf("物化视图: %s 定义: %s（能满足需求时优先查询该物化视图）\n", mv.Name, strings.Join(strings.Fields(mv.Definition), " ")))
+    }
+    return b.String()
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+    "encoding/json"
+    "fmt"
+    "hash/fnv"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "chat2sr/config"
+)
+
+// TableScore 表与问题的相关度
+type TableScore struct {
+    Table string  `json:"table"`
+    Score float64 `json:"score"`
+}
+
+type tableIndexEntry struct {
+    Hash   uint64    `json:"hash"`
+    Vector []float32 `json:"vector"`
+}
+
+// TableIndex 表的向量索引，持久化在本地磁盘
+type TableIndex struct {
+    mu       sync.RWMutex
+    provider EmbeddingProvider
+    path     string
+    // 串行执行更新，避免并发的表结构刷新重复计算向量、互相覆盖索引文件
+    updateMu sync.Mutex
+
+    Provider string                      `json:"provider"`
+    Entries  map[string]*tableIndexEntry `json:"entries"`
+}
+
+var (
+    tableIndexesMu sync.Mutex
+    tableIndexes   = make(map[string]*TableIndex)
+)
+
+// GetTableIndex 获取数据源的表向量索引，首次调用时从磁盘加载
+func GetTableIndex(datasource string) *TableIndex {
+    tableIndexesMu.Lock()
+    defer tableIndexesMu.Unlock()
+
+    if index, ok := tableIndexes[datasource]; ok {
+        return index
+    }
+
+    provider := NewEmbeddingProvider()
+    index := &TableIndex{
+        provider: provider,
+        path:     filepath.Join(config.AppConfig.DataDir, "embeddings", datasource+".json"),
+        Provider: provider.Name(),
+        Entries:  make(map[string]*tableIndexEntry),
+    }
+    if err := index.load(); err != nil {
+        log.Printf("Error loading table index for %s: %s", datasource, err.Error())
+    }
+    tableIndexes[datasource] = index
+    return index
+}
+
+// UpdateTableIndex 根据最新的表结构增量更新向量，只重新计算内容发生变化的表
+func UpdateTableIndex(catalog *SchemaCatalog) {
+    index := GetTableIndex(catalog.Datasource)
+    index.updateMu.Lock()
+    defer index.updateMu.Unlock()
+
+    var names, texts []string
+    hashes := make(map[string]uint64)
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+        text := tableDocument(table)
+        h := fnv.New64a()
+        h.Write([]byte(text))
+        hashes[table.Name] = h.Sum64()
+
+        index.mu.RLock()
+        entry, ok := index.Entries[table.Name]
+        index.mu.RUnlock()
+        if !ok || entry.Hash != hashes[table.Name] {
+            names = append(names, table.Name)
+            texts = append(texts, text)
+        }
+    }
+
+    var vectors [][]float32
+    if len(texts) > 0 {
+        var err error
+        vectors, err = index.provider.Embed(texts)
+        if err != nil {
+            log.Printf("Error embedding tables for %s: %s", catalog.Datasource, err.Error())
+            return
+        }
+    }
+
+    index.mu.Lock()
+    for i, name := range names {
+        index.Entries[name] = &tableIndexEntry{Hash: hashes[name], Vector: vectors[i]}
+    }
+    // 删除已不存在的表
+    for name := range index.Entries {
+        if _, ok := hashes[name]; !ok {
+            delete(index.Entries, name)
+        }
+    }
+    index.mu.Unlock()
+
+    if err := index.save(); err != nil {
+        log.Printf("Error saving table index for %s: %s", catalog.Datasource, err.Error())
+    }
+    log.Printf("Updated table index for %s: %d tables embedded, %d total", catalog.Datasource, len(names), len(hashes))
+}
+
+// Search 按余弦相似度返回与问题最相关的表
+func (idx *TableIndex) Search(question string, topK int, minScore float64) ([]TableScore, error) {
+    vectors, err := idx.provider.Embed([]string{question})
+    if err != nil {
+        return nil, err
+    }
+
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    var scores []TableScore
+    for name, entry := range idx.Entries {
+        score := cosineSimilarity(vectors[0], entry.Vector)
+        if score >= minScore {
+            scores = append(scores, TableScore{Table: name, Score: score})
+        }
+    }
+    sort.Slice(scores, func(i, j int) bool {
+        if scores[i].Score == scores[j].Score {
+            return scores[i].Table < scores[j].Table
+        }
+        return scores[i].Score > scores[j].Score
+    })
+    if len(scores) > topK {
+        scores = scores[:topK]
+    }
+    return scores, nil
+}
+
+// Size 索引中的表数量
+func (idx *TableIndex) Size() int {
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+    return len(idx.Entries)
+}
+
+// tableDocument 拼接表名、表注释和字段说明作为向量化的文本
+func tableDocument(table *TableSchema) string {
+    var b strings.Builder
+    b.WriteString(table.Name)
+    if table.Comment != "" {
+        b.WriteString(" " + table.Comment)
+    }
+    for _, col := range table.Columns {
+        b.WriteString("\n" + col.Name)
+        if col.Comment != "" {
+            b.WriteString(" " + col.Comment)
+        }
+    }
+    return b.String()
+}
+
+func (idx *TableIndex) load() error {
+    data, err := os.ReadFile(idx.path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil
+        }
+        return err
+    }
+
+    var stored TableIndex
+    if err := json.Unmarshal(data, &stored); err != nil {
+        return fmt.Errorf("failed to decode table index: %v", err)
+    }
+    // 向量化服务变化后旧向量不可比较，丢弃重建
+    if stored.Provider != idx.Provider {
+        log.Printf("Embedding provider changed from %s to %s, rebuilding table index", stored.Provider, idx.Provider)
+        return nil
+    }
+    if stored.Entries != nil {
+        idx.Entries = stored.Entries
+    }
+    return nil
+}
+
+func (idx *TableIndex) save() error {
+    idx.mu.RLock()
+    data, err := json.Marshal(idx)
+    idx.mu.RUnlock()
+    if err != nil {
+        return fmt.Errorf("failed to encode table index: %v", err)
+    }
+    return writeFileAtomic(idx.path, data)
+}
+
+// writeFileAtomic 先写同目录下唯一命名的临时文件再重命名，避免进程中断时留下不完整的文件，
+// 并发写同一文件时也不会互相覆盖临时文件
+func writeFileAtomic(path string, data []byte) error {
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(0644); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
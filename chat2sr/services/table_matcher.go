@@ -14,6 +14,12 @@ func FilterTablesByKeywords(tables []TableInfo, userInput string) []TableInfo {
     
     // 将用户输入转换为小写
     userInput = strings.ToLower(userInput)
+
+    // 对用户输入分词并补充别名，中文问题可以匹配英文表名
+    userWords := ExpandAliases(Tokenize(userInput))
+
+    // 问题中出现的业务术语所对应的表
+    glossaryTables := GlossaryTables(MatchGlossary(userInput))
     
     // 计算每个表与用户输入的相关度
     type tableScore struct {
@@ -47,22 +53,23 @@ func FilterTablesByKeywords(tables []TableInfo, userInput string) []TableInfo {
                 score += 0.7
             }
             
-            // 检查注释中的关键词
-            userWords := strings.Fields(userInput)
+            // 检查注释分词后与用户输入的重合词
+            commentWords := tokenSet(Tokenize(commentLower))
             for _, word := range userWords {
-                if len(word) > 2 && strings.Contains(commentLower, word) {
+                if commentWords[word] {
                     score += 0.3
                 }
             }
         }
         
         // 3. 将表名拆分为单词（按下划线或驼峰命名）
-        tableWords := splitTableName(tableLower)
+        tableWords := Tokenize(table.Name)
+        userWordSet := tokenSet(userWords)
         
-        // 4. 计算用户输入中包含多少个表名单词
+        // 4. 计算用户输入（含别名）中包含多少个表名单词
         matchCount := 0
         for _, word := range tableWords {
-            if len(word) > 1 && strings.Contains(userInput, word) {
+            if userWordSet[word] {
                 matchCount++
             }
         }
@@ -71,16 +78,27 @@ func FilterTablesByKeywords(tables []TableInfo, userInput string) []TableInfo {
             // 计算匹配率
             matchRatio := float64(matchCount) / float64(len(tableWords))
             score += 0.4 * matchRatio
-        } else {
-            // 5. 检查用户输入的单词是否出现在表名中
-            userWords := strings.Fields(userInput)
+        }
+
+        // 5. 检查字段名和字段注释，表结构缓存未加载时跳过
+        columnScore := 0.0
+        for _, col := range cachedColumns(table.Name) {
+            columnWords := tokenSet(Tokenize(col.Name + " " + col.Comment))
             for _, word := range userWords {
-                if len(word) > 2 && strings.Contains(tableLower, word) {
-                    score += 0.2
-                    break
+                if columnWords[word] {
+                    columnScore += 0.1
                 }
             }
         }
+        if columnScore > 0.5 {
+            columnScore = 0.5
+        }
+        score += columnScore
+
+        // 6. 业务术语明确对应的表
+        if containsString(glossaryTables, table.Name) {
+            score += 1.5
+        }
         
         // 只有得分大于0的表才加入结果
         if score > 0 {
@@ -113,35 +131,24 @@ func FilterTablesByKeywords(tables []TableInfo, userInput string) []TableInfo {
     return result
 }
 
-// splitTableName 将表名分解为单词
-func splitTableName(tableName string) []string {
-    // 处理下划线分隔的表名
-    if strings.Contains(tableName, "_") {
-        parts := strings.Split(tableName, "_")
-        var words []string
-        for _, part := range parts {
-            if part != "" {
-                words = append(words, part)
-            }
-        }
-        return words
+// tokenSet 将分词结果转换为集合
+func tokenSet(tokens []string) map[string]bool {
+    set := make(map[string]bool, len(tokens))
+    for _, token := range tokens {
+        set[token] = true
     }
-    
-    // 处理驼峰命名的表名
-    var words []string
-    var currentWord strings.Builder
-    
-    for i, char := range tableName {
-        if i > 0 && (char >= 'A' && char <= 'Z') {
-            words = append(words, strings.ToLower(currentWord.String()))
-            currentWord.Reset()
-        }
-        currentWord.WriteRune(char)
+    return set
+}
+
+// cachedColumns 从已加载的表结构缓存中读取字段，不触发数据库查询
+func cachedColumns(tableName string) []ColumnInfo {
+    catalog, err := GetCatalog(DefaultDatasource)
+    if err != nil || !catalog.Loaded() {
+        return nil
     }
-    
-    if currentWord.Len() > 0 {
-        words = append(words, strings.ToLower(currentWord.String()))
+    table, ok := catalog.Table(tableName)
+    if !ok {
+        return nil
     }
-    
-    return words
-}
\ No newline at end of file
+    return table.Columns
+}
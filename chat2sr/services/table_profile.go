@@ -0,0 +1,209 @@
+package services
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "chat2sr/config"
+)
+
+var (
+    ErrTableNotFound   = errors.New("table not found")
+    ErrTableNotAllowed = errors.New("table is deprecated")
+    ErrColumnsNotFound = errors.New("no matching columns")
+)
+
+// ColumnStats 字段统计信息
+type ColumnStats struct {
+    ColumnProfile
+    Type      string  `json:"type"`
+    NullRatio float64 `json:"null_ratio"`
+}
+
+// TableProfile 表的字段统计
+type TableProfile struct {
+    Table      string        `json:"table"`
+    RowCount   int64         `json:"row_count"`
+    SampleRows int64         `json:"sample_rows"`
+    Columns    []ColumnStats `json:"columns"`
+}
+
+// 这些类型不支持 MIN/MAX/approx_count_distinct，只统计空值比例
+var complexTypePrefixes = []string{"array", "map", "struct", "json", "bitmap", "hll", "percentile"}
+
+// LookupTable 校验表是否存在且允许访问，返回表结构。已标记为废弃的表不允许预览和统计
+func LookupTable(tableName string) (*TableSchema, error) {
+    if IsTableDeprecated(tableName) {
+        return nil, ErrTableNotAllowed
+    }
+    return findTable(tableName)
+}
+
+// findTable 只校验表是否存在，用于解释和改写已有SQL，不受废弃标记的限制
+func findTable(tableName string) (*TableSchema, error) {
+    tables, err := CatalogTables()
+    if err != nil {
+        return nil, err
+    }
+    for _, table := range tables {
+        if table.Name == tableName {
+            return CatalogTable(tableName)
+        }
+    }
+    return nil, ErrTableNotFound
+}
+
+// PreviewTable 获取表的前N行数据，格式与 ExecuteSQL 一致
+func PreviewTable(ctx context.Context, tableName string, limit int) ([]string, []map[string]interface{}, error) {
+    table, err := LookupTable(tableName)
+    if err != nil {
+        return nil, nil, err
+    }
+    return previewTable(ctx, table, limit)
+}
+
+func previewTable(ctx context.Context, table *TableSchema, limit int) ([]string, []map[string]interface{}, error) {
+    query := fmt.Sprintf("SELECT * FROM `%s` LIMIT %d", table.Name, limit)
+    return ExecuteSQLContext(ctx, query, nil)
+}
+
+// ProfileTable 统计表中字段的空值比例、近似基数、最小最大值及高频取值，columns 为空时统计所有字段。
+// 空值比例、近似基数和高频取值只统计前 PROFILE_SAMPLE_ROWS 行，近似基数超过 PROFILE_MAX_DISTINCT 的字段不统计高频取值
+func ProfileTable(ctx context.Context, tableName string, columns []string) (*TableProfile, error) {
+    table, err := LookupTable(tableName)
+    if err != nil {
+        return nil, err
+    }
+    return profileTable(ctx, table, columns)
+}
+
+func profileTable(ctx context.Context, table *TableSchema, columns []string) (*TableProfile, error) {
+    tableName := table.Name
+    var selected []ColumnInfo
+    for _, col := range table.Columns {
+        if len(columns) == 0 || containsString(columns, col.Name) {
+            selected = append(selected, col)
+        }
+    }
+    if len(selected) == 0 {
+        return nil, fmt.Errorf("%w in table %s", ErrColumnsNotFound, tableName)
+    }
+
+    dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+        config.AppConfig.DBUser,
+        config.AppConfig.DBPassword,
+        config.AppConfig.DBHost,
+        config.AppConfig.DBPort,
+        config.AppConfig.DBName)
+
+    db, err := sql.Open("mysql", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to database: %v", err)
+    }
+    defer db.Close()
+
+    // 行数和数值、日期字段的最小最大值按全表计算（StarRocks 可利用 zone map，开销较小）
+    totals := []string{"COUNT(*)"}
+    for _, col := range selected {
+        if isRangeType(col.Type) {
+            totals = append(totals, fmt.Sprintf("MIN(`%s`)", col.Name), fmt.Sprintf("MAX(`%s`)", col.Name))
+        }
+    }
+    totalValues, err := scanProfileRow(ctx, db, totals, "`"+tableName+"`")
+    if err != nil {
+        return nil, fmt.Errorf("failed to profile table %s: %v", tableName, err)
+    }
+
+    // 空值比例、近似基数和高频取值只统计前 PROFILE_SAMPLE_ROWS 行，避免对大表全表扫描
+    source := fmt.Sprintf("`%s`", tableName)
+    if rows := config.AppConfig.ProfileSampleRows; rows > 0 {
+        names := make([]string, len(selected))
+        for i, col := range selected {
+            names[i] = "`" + col.Name + "`"
+        }
+        source = fmt.Sprintf("(SELECT %s FROM `%s` LIMIT %d) sampled", strings.Join(names, ", "), tableName, rows)
+    }
+    selects := []string{"COUNT(*)"}
+    for _, col := range selected {
+        selects = append(selects, fmt.Sprintf("SUM(CASE WHEN `%s` IS NULL THEN 1 ELSE 0 END)", col.Name))
+        if isComplexType(col.Type) {
+            continue
+        }
+        selects = append(selects, fmt.Sprintf("approx_count_distinct(`%s`)", col.Name))
+        if !isRangeType(col.Type) {
+            selects = append(selects, fmt.Sprintf("MIN(`%s`)", col.Name), fmt.Sprintf("MAX(`%s`)", col.Name))
+        }
+    }
+    values, err := scanProfileRow(ctx, db, selects, source)
+    if err != nil {
+        return nil, fmt.Errorf("failed to profile table %s: %v", tableName, err)
+    }
+
+    profile := &TableProfile{Table: tableName}
+    profile.RowCount, _ = strconv.ParseInt(totalValues[0].String, 10, 64)
+    profile.SampleRows, _ = strconv.ParseInt(values[0].String, 10, 64)
+
+    idx, totalIdx := 1, 1
+    for _, col := range selected {
+        stats := ColumnStats{ColumnProfile: ColumnProfile{Column: col.Name}, Type: col.Type}
+
+        nulls, _ := strconv.ParseInt(values[idx].String, 10, 64)
+        idx++
+        if profile.SampleRows > 0 {
+            stats.NullRatio = float64(nulls) / float64(profile.SampleRows)
+        }
+
+        if !isComplexType(col.Type) {
+            stats.DistinctCount, _ = strconv.ParseInt(values[idx].String, 10, 64)
+            idx++
+            if isRangeType(col.Type) {
+                stats.Min = totalValues[totalIdx].String
+                stats.Max = totalValues[totalIdx+1].String
+                totalIdx += 2
+            } else {
+                stats.Min = values[idx].String
+                stats.Max = values[idx+1].String
+                idx += 2
+            }
+
+            if stats.DistinctCount <= int64(config.AppConfig.ProfileMaxDistinct) {
+                topValues, err := getTopValues(ctx, db, source, col.Name, config.AppConfig.ProfileTopN)
+                if err != nil {
+                    return nil, err
+                }
+                stats.TopValues = topValues
+            }
+        }
+
+        profile.Columns = append(profile.Columns, stats)
+    }
+
+    return profile, nil
+}
+
+// scanProfileRow 对 source 执行一次聚合查询，取值统一按字符串读取
+func scanProfileRow(ctx context.Context, db *sql.DB, selects []string, source string) ([]sql.NullString, error) {
+    query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), source)
+    values := make([]sql.NullString, len(selects))
+    pointers := make([]interface{}, len(selects))
+    for i := range values {
+        pointers[i] = &values[i]
+    }
+    if err := db.QueryRowContext(ctx, query).Scan(pointers...); err != nil {
+        return nil, err
+    }
+    return values, nil
+}
+
+func isComplexType(columnType string) bool {
+    t := strings.ToLower(columnType)
+    for _, prefix := range complexTypePrefixes {
+        if strings.HasPrefix(t, prefix) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+    "bufio"
+    _ "embed"
+    "strings"
+    "sync"
+    "unicode"
+)
+
+//go:embed dict/words.txt
+var wordsDict string
+
+//go:embed dict/stopwords.txt
+var stopwordsDict string
+
+//go:embed dict/aliases.txt
+var aliasesDict string
+
+// 词典中最长词的字数，最大匹配时的窗口大小
+const maxWordLength = 6
+
+var (
+    dictWords = loadWordSet(wordsDict)
+    stopWords = loadWordSet(stopwordsDict)
+    // 别名双向映射：中文词 -> 英文/拼音，英文/拼音 -> 中文词
+    wordAliases = loadAliases(aliasesDict)
+
+    // 从表和字段注释、业务术语中收录的领域词，只增不减
+    domainWordsMu sync.RWMutex
+    domainWords   = make(map[string]bool)
+)
+
+// Tokenize 分词：中文按词典正向最大匹配，英文按非字母数字、下划线和驼峰拆分，统一小写并去除停用词
+func Tokenize(text string) []string {
+    var tokens []string
+    var word []rune
+    var han []rune
+
+    flushWord := func() {
+        if len(word) > 0 {
+            for _, part := range splitCamelCase(string(word)) {
+                tokens = append(tokens, strings.ToLower(part))
+            }
+            word = word[:0]
+        }
+    }
+    flushHan := func() {
+        if len(han) > 0 {
+            tokens = append(tokens, segmentChinese(han)...)
+            han = han[:0]
+        }
+    }
+
+    for _, r := range text {
+        switch {
+        case unicode.Is(unicode.Han, r):
+            flushWord()
+            han = append(han, r)
+        case unicode.IsLetter(r) || unicode.IsDigit(r):
+            flushHan()
+            word = append(word, r)
+        default:
+            flushWord()
+            flushHan()
+        }
+    }
+    flushWord()
+    flushHan()
+
+    result := tokens[:0]
+    for _, token := range tokens {
+        if !stopWords[token] {
+            result = append(result, token)
+        }
+    }
+    return result
+}
+
+// ExpandAliases 在分词结果后追加别名，使中文问题能匹配英文表名，英文或拼音也能匹配中文注释
+func ExpandAliases(tokens []string) []string {
+    seen := make(map[string]bool, len(tokens))
+    result := make([]string, 0, len(tokens))
+    for _, token := range tokens {
+        if !seen[token] {
+            seen[token] = true
+            result = append(result, token)
+        }
+    }
+    for _, token := range tokens {
+        for _, alias := range wordAliases[token] {
+            if !seen[alias] {
+                seen[alias] = true
+                result = append(result, alias)
+            }
+        }
+    }
+    return result
+}
+
+// segmentChinese 正向最大匹配分词，词典中没有的字单独成词
+func segmentChinese(text []rune) []string {
+    domainWordsMu.RLock()
+    defer domainWordsMu.RUnlock()
+    return segmentRunes(text, true)
+}
+
+// segmentRunes 按内置词典和领域词做最大匹配，调用方需持有 domainWordsMu 读锁。
+// 命中领域词时同时输出按内置词典切分的结果，问题中只提到其中一部分时仍能匹配，如"订单创建时间"也输出"创建"、"时间"
+func segmentRunes(text []rune, withDomain bool) []string {
+    var words []string
+    for i := 0; i < len(text); {
+        end := i + maxWordLength
+        if end > len(text) {
+            end = len(text)
+        }
+        matched := 1
+        domain := false
+        for j := end; j > i+1; j-- {
+            candidate := string(text[i:j])
+            if dictWords[candidate] || stopWords[candidate] {
+                matched = j - i
+                break
+            }
+            if withDomain && domainWords[candidate] {
+                matched = j - i
+                domain = true
+                break
+            }
+        }
+        words = append(words, string(text[i:i+matched]))
+        if domain {
+            words = append(words, segmentRunes(text[i:i+matched], false)...)
+        }
+        i += matched
+    }
+    return words
+}
+
+// AddDictWords 将文本中 2 到 maxWordLength 个字的连续中文加入分词词典，返回新增的词数。
+// 内置词典只覆盖通用词，表和字段注释、业务术语中的领域词需在构建索引前加入，使索引和问题按相同的词切分
+func AddDictWords(texts []string) int {
+    domainWordsMu.Lock()
+    defer domainWordsMu.Unlock()
+
+    added := 0
+    for _, text := range texts {
+        var run []rune
+        flush := func() {
+            if n := len(run); n >= 2 && n <= maxWordLength {
+                word := string(run)
+                if !dictWords[word] && !stopWords[word] && !domainWords[word] {
+                    domainWords[word] = true
+                    added++
+                }
+            }
+            run = run[:0]
+        }
+        for _, r := range text {
+            if unicode.Is(unicode.Han, r) {
+                run = append(run, r)
+            } else {
+                flush()
+            }
+        }
+        flush()
+    }
+    return added
+}
+
+// splitCamelCase 按驼峰拆分英文单词，如 orderDetail -> order, Detail
+func splitCamelCase(word string) []string {
+    var parts []string
+    runes := []rune(word)
+    start := 0
+    for i := 1; i < len(runes); i++ {
+        if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+            parts = append(parts, string(runes[start:i]))
+            start = i
+        }
+    }
+    return append(parts, string(runes[start:]))
+}
+
+func loadWordSet(content string) map[string]bool {
+    words := make(map[string]bool)
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        words[strings.ToLower(line)] = true
+    }
+    return words
+}
+
+func loadAliases(content string) map[string][]string {
+    aliases := make(map[string][]string)
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fields := strings.SplitN(line, "\t", 2)
+        if len(fields) != 2 {
+            continue
+        }
+        word := strings.TrimSpace(fields[0])
+        for _, alias := range strings.Split(fields[1], ",") {
+            alias = strings.ToLower(strings.TrimSpace(alias))
+            if alias == "" {
+                continue
+            }
+            // 带下划线的别名拆成与表名分词一致的形式，只做正向映射，避免 id 之类的片段反向命中
+            if strings.Contains(alias, "_") {
+                for _, part := range strings.Split(alias, "_") {
+                    if part != "" {
+                        aliases[word] = appendUnique(aliases[word], part)
+                    }
+                }
+                continue
+            }
+            aliases[word] = appendUnique(aliases[word], alias)
+            aliases[alias] = appendUnique(aliases[alias], word)
+        }
+    }
+    return aliases
+}
+
+func appendUnique(list []string, s string) []string {
+    for _, item := range list {
+        if item == s {
+            return list
+        }
+    }
+    return append(list, s)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestAddDictWords(t *testing.T) {
+    domainWordsMu.Lock()
+    saved := domainWords
+    domainWords = make(map[string]bool)
+    domainWordsMu.Unlock()
+    t.Cleanup(func() {
+        domainWordsMu.Lock()
+        domainWords = saved
+        domainWordsMu.Unlock()
+    })
+
+    before := Tokenize("拉新成本")
+    if added := AddDictWords([]string{"拉新成本（按月）", "用户ID", "这是一段超过六个字的注释"}); added != 1 {
+        t.Errorf("AddDictWords added %d words, want 1", added)
+    }
+
+    got := Tokenize("拉新成本")
+    want := append([]string{"拉新成本"}, before...)
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Tokenize(拉新成本) = %v, want %v", got, want)
+    }
+    if got := Tokenize("用户"); len(got) != 1 || got[0] != "用户" {
+        t.Errorf("Tokenize(用户) = %v, want [用户]", got)
+    }
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "log"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+    "chat2sr/config"
+)
+
+// ValueCount 字段取值及出现次数
+type ValueCount struct {
+    Value string `json:"value"`
+    Count int64  `json:"count"`
+}
+
+// ColumnProfile 字段取值概况
+type ColumnProfile struct {
+    Column        string       `json:"column"`
+    TopValues     []ValueCount `json:"top_values,omitempty"`
+    DistinctCount int64        `json:"distinct_count"`
+    Min           string       `json:"min,omitempty"`
+    Max           string       `json:"max,omitempty"`
+}
+
+var (
+    profilesMu sync.RWMutex
+    // 表名 -> 字段名 -> 取值概况
+    profiles = make(map[string]map[string]*ColumnProfile)
+)
+
+// 字段名包含这些词时，通常是取值有限的枚举字段
+var enumColumnHints = []string{
+    "status", "state", "type", "kind", "category", "level", "grade", "channel",
+    "source", "platform", "city", "province", "region", "country", "gender",
+    "sex", "flag", "is_", "tag", "stage",
+}
+
+// StartValueProfiler 等待表结构加载完成后在后台采集字段取值，并按配置的间隔重新采集，间隔不大于0时只采集一次
+func StartValueProfiler() {
+    if !config.AppConfig.ProfileEnabled {
+        return
+    }
+
+    go func() {
+        catalog, err := GetCatalog(DefaultDatasource)
+        if err != nil {
+            log.Printf("Value profiler disabled: %s", err.Error())
+            return
+        }
+        for !catalog.Loaded() {
+            time.Sleep(5 * time.Second)
+        }
+
+        profileCatalog(catalog)
+        interval := config.AppConfig.ProfileInterval
+        if interval <= 0 {
+            return
+        }
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            profileCatalog(catalog)
+        }
+    }()
+}
+
+// GetColumnProfile 获取缓存的字段取值概况
+func GetColumnProfile(table, column string) (*ColumnProfile, bool) {
+    profilesMu.RLock()
+    defer profilesMu.RUnlock()
+
+    profile, ok := profiles[table][column]
+    return profile, ok
+}
+
+func profileCatalog(catalog *SchemaCatalog) {
+    dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+        config.AppConfig.DBUser,
+        config.AppConfig.DBPassword,
+        config.AppConfig.DBHost,
+        config.AppConfig.DBPort,
+        config.AppConfig.DBName)
+
+    db, err := sql.Open("mysql", dsn)
+    if err != nil {
+        log.Printf("Error connecting to database for profiling: %s", err.Error())
+        return
+    }
+    defer db.Close()
+
+    start := time.Now()
+    configured := parseProfileColumns(config.AppConfig.ProfileColumns)
+    count := 0
+
+    for _, info := range catalog.Tables() {
+        table, ok := catalog.Table(info.Name)
+        if !ok {
+            continue
+        }
+
+        for _, col := range table.Columns {
+            explicit := configured[table.Name+"."+col.Name]
+            if !explicit && !shouldProfileColumn(col) {
+                continue
+            }
+
+            profile, err := profileColumn(db, table.Name, col, explicit)
+            if err != nil {
+                log.Printf("Error profiling %s.%s: %s", table.Name, col.Name, err.Error())
+                continue
+            }
+
+            profilesMu.Lock()
+            if profiles[table.Name] == nil {
+                profiles[table.Name] = make(map[string]*ColumnProfile)
+            }
+            profiles[table.Name][col.Name] = profile
+            profilesMu.Unlock()
+            count++
+        }
+    }
+
+    log.Printf("Profiled %d columns in %s", count, time.Since(start))
+}
+
+// parseProfileColumns 解析 PROFILE_COLUMNS 配置，格式为 table.column,table.column
+func parseProfileColumns(value string) map[string]bool {
+    columns := make(map[string]bool)
+    for _, item := range strings.Split(value, ",") {
+        item = strings.TrimSpace(item)
+        if item != "" {
+            columns[item] = true
+        }
+    }
+    return columns
+}
+
+// shouldProfileColumn 启发式判断字段是否值得采集：小数和日期字段采集范围（整数字段多为ID，跳过），枚举类字段采集取值
+func shouldProfileColumn(col ColumnInfo) bool {
+    if isComplexType(col.Type) {
+        return false
+    }
+    if isRangeType(col.Type) && !strings.Contains(strings.ToLower(col.Type), "int") {
+        return true
+    }
+
+    name := strings.ToLower(col.Name)
+    for _, hint := range enumColumnHints {
+        if strings.Contains(name, hint) {
+            return true
+        }
+    }
+    return false
+}
+
+// isRangeType 数值或日期类型，采集最小值和最大值
+func isRangeType(columnType string) bool {
+    t := strings.ToLower(columnType)
+    for _, prefix := range []string{"tinyint", "smallint", "int", "bigint", "largeint", "decimal", "float", "double", "date", "datetime"} {
+        if strings.HasPrefix(t, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// profileColumn 采集单个字段的近似基数、最小最大值，以及低基数字段的高频取值。
+// 配置了 PROFILE_SAMPLE_ROWS 时基数和高频取值只统计前N行，避免后台任务反复全表扫描；
+// 前N行是任意的一段数据，最小最大值仍按全表计算（StarRocks 可利用 zone map，开销较小）
+func profileColumn(db *sql.DB, table string, col ColumnInfo, explicit bool) (*ColumnProfile, error) {
+    profile := &ColumnProfile{Column: col.Name}
+
+    source := fmt.Sprintf("`%s`", table)
+    if rows := config.AppConfig.ProfileSampleRows; rows > 0 {
+        source = fmt.Sprintf("(SELECT `%s` FROM `%s` LIMIT %d) sampled", col.Name, table, rows)
+    }
+
+    query := fmt.Sprintf("SELECT approx_count_distinct(`%s`) FROM %s", col.Name, source)
+    if err := db.QueryRow(query).Scan(&profile.DistinctCount); err != nil {
+        return nil, fmt.Errorf("failed to get column stats: %v", err)
+    }
+    if isRangeType(col.Type) {
+        var min, max sql.NullString
+        query := fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM `%s`", col.Name, col.Name, table)
+        if err := db.QueryRow(query).Scan(&min, &max); err != nil {
+            return nil, fmt.Errorf("failed to get column range: %v", err)
+        }
+        profile.Min = min.String
+        profile.Max = max.String
+    }
+
+    // 高基数字段的取值示例意义不大，只有显式配置时才采集
+    if !explicit && profile.DistinctCount > int64(config.AppConfig.ProfileMaxDistinct) {
+        return profile, nil
+    }
+
+    topValues, err := getTopValues(context.Background(), db, source, col.Name, config.AppConfig.ProfileTopN)
+    if err != nil {
+        return nil, err
+    }
+    profile.TopValues = topValues
+    return profile, nil
+}
+
+// getTopValues 获取字段出现次数最多的前N个取值，source 为 FROM 之后的表或采样子查询
+func getTopValues(ctx context.Context, db *sql.DB, source, column string, limit int) ([]ValueCount, error) {
+    query := fmt.Sprintf("SELECT `%s`, COUNT(*) AS cnt FROM %s WHERE `%s` IS NOT NULL GROUP BY `%s` ORDER BY cnt DESC LIMIT %d",
+        column, source, column, column, limit)
+    rows, err := db.QueryContext(ctx, query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get top values: %v", err)
+    }
+    defer rows.Close()
+
+    var values []ValueCount
+    for rows.Next() {
+        var value sql.NullString
+        var count int64
+        if err := rows.Scan(&value, &count); err != nil {
+            return nil, err
+        }
+        values = append(values, ValueCount{Value: value.String, Count: count})
+    }
+    return values, rows.Err()
+}
+
+// FormatColumnSamples 格式化字段的取值示例，没有缓存时返回空字符串
+func FormatColumnSamples(table, column string) string {
+    // dbt accepted_values 测试声明的取值是完整的枚举，优先于采样得到的高频值
+    if accepted := AcceptedValues(table, column); len(accepted) > 0 {
+        values := make([]string, 0, len(accepted))
+        for _, v := range accepted {
+            values = append(values, "'"+v+"'")
+        }
+        return "可选值: " + strings.Join(values, ", ")
+    }
+
+    profile, ok := GetColumnProfile(table, column)
+    if !ok {
+        return ""
+    }
+
+    var parts []string
+    if len(profile.TopValues) > 0 {
+        values := make([]string, 0, len(profile.TopValues))
+        for _, v := range profile.TopValues {
+            values = append(values, "'"+v.Value+"'")
+        }
+        parts = append(parts, "可选值: "+strings.Join(values, ", "))
+    }
+    if profile.Min != "" || profile.Max != "" {
+        parts = append(parts, fmt.Sprintf("范围: %s ~ %s", profile.Min, profile.Max))
+    }
+    return strings.Join(parts, "; ")
+}
+
+// EstimateTokens 粗略估算文本的token数：中文按每字1个token，其他字符按每4个1个token
+func EstimateTokens(text string) int {
+    cjk, other := 0, 0
+    for _, r := range text {
+        if unicode.Is(unicode.Han, r) {
+            cjk++
+        } else {
+            other++
+        }
+    }
+    return cjk + (other+3)/4
+}